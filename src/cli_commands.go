@@ -0,0 +1,125 @@
+// This file handles the subcommands of the CLI (build, serve, validate, init, export).
+// For compatibility, the flags from before the subcommands (eg: linkitall -i dir -s) still
+// work. They are used when the first argument is a flag (see CliArgs).
+package main
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	argparse "github.com/alexflint/go-arg"
+)
+
+// Options of the build subcommand
+type BuildArgs struct {
+	InputDir   string `arg:"positional,required" placeholder:"INDIR" help:"path to the input directory"`
+	GraphFile  string `arg:"-g,--graph" default:"graph.yaml" help:"input graph base filename"`
+	OutputDir  string `arg:"-d,--outdir" help:"path to the output directory [default: indir]"`
+	OutFile    string `arg:"-o,--out" default:"index.html" help:"output html base filename"`
+	Release    bool   `arg:"-r,--release" help:"run in release mode"`
+	Overwrite  bool   `arg:"--overwrite" help:"overwrite asset files"`
+	Extract    bool   `arg:"--extract-assets" help:"extract asset and vendor files to outdir and exit"`
+	SingleFile bool   `arg:"--single-file" help:"inline CSS and JS files in the output html"`
+	InlineImgs bool   `arg:"--inline-images" help:"inline small local image resources in the html"`
+}
+
+// Options of the serve subcommand. Same as build, with the server options.
+type ServeArgs struct {
+	BuildArgs
+	ServerAddr string `arg:"-l,--listen" default:":8101" help:"listen address"`
+	NoInteract bool   `arg:"--no-interactive" help:"do not read commands from stdin"`
+}
+
+// Arguments of the CLI with subcommands. Only one of the fields is set.
+type CommandArgs struct {
+	Build    *BuildArgs    `arg:"subcommand:build" help:"generate the HTML page for the graph"`
+	Serve    *ServeArgs    `arg:"subcommand:serve" help:"serve the page and rebuild on changes"`
+	Validate *ValidateArgs `arg:"subcommand:validate" help:"check the graph without writing output"`
+	Init     *InitArgs     `arg:"subcommand:init" help:"create a new graph directory"`
+	Export   *ExportArgs   `arg:"subcommand:export" help:"write the graph in another format"`
+}
+
+func (CommandArgs) Description() string {
+	return "Generate an interactive HTML page for a dependency graph.\n" +
+		"The flags without a subcommand (eg: linkitall -i dir -s) are still supported.\n"
+}
+
+// Convert the build options to the options used for processing
+func (build *BuildArgs) toCliArgs() CliArgs {
+	return CliArgs{
+		Release:    build.Release,
+		InputDir:   build.InputDir,
+		GraphFile:  build.GraphFile,
+		OutputDir:  build.OutputDir,
+		OutFile:    build.OutFile,
+		Overwrite:  build.Overwrite,
+		Extract:    build.Extract,
+		SingleFile: build.SingleFile,
+		InlineImgs: build.InlineImgs,
+	}
+}
+
+// Convert the serve options to the options used for processing
+func (serve *ServeArgs) toCliArgs() CliArgs {
+	args := serve.BuildArgs.toCliArgs()
+	args.ServerMode = true
+	args.ServerAddr = serve.ServerAddr
+	args.NoInteract = serve.NoInteract
+	return args
+}
+
+// Return the graph file for the given path. A directory means graph.yaml inside it.
+func getGraphFilePath(path string) string {
+	if isPathAccessible(path, "dir") {
+		return filepath.Join(path, "graph.yaml")
+	}
+	return path
+}
+
+// Check if the arguments are for the legacy CLI (flags without a subcommand)
+func isLegacyCommandLine(args []string) bool {
+	if len(args) == 0 || !strings.HasPrefix(args[0], "-") {
+		return false
+	}
+	// Help is shown for the CLI with subcommands
+	return args[0] != "-h" && args[0] != "--help"
+}
+
+// Parse the command line and run the command. Returns the exit status.
+func runCommandLine() int {
+	if isLegacyCommandLine(os.Args[1:]) {
+		var args CliArgs
+		argparse.MustParse(&args)
+		return exitStatusForError(runBuildOrServe(&args))
+	}
+
+	var command CommandArgs
+	parser := argparse.MustParse(&command)
+	switch {
+	case command.Build != nil:
+		args := command.Build.toCliArgs()
+		return exitStatusForError(runBuildOrServe(&args))
+	case command.Serve != nil:
+		args := command.Serve.toCliArgs()
+		return exitStatusForError(runBuildOrServe(&args))
+	case command.Validate != nil:
+		return runValidateCommand(command.Validate)
+	case command.Init != nil:
+		return exitStatusForError(runInitCommand(command.Init))
+	case command.Export != nil:
+		return exitStatusForError(runExportCommand(command.Export))
+	}
+	parser.WriteHelp(os.Stdout)
+	return 1
+}
+
+// Print the error (if any) and return the exit status for it
+func exitStatusForError(err error) int {
+	if err != nil {
+		log.Printf("Error: %s", err)
+		return 1
+	}
+	return 0
+}
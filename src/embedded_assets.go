@@ -0,0 +1,67 @@
+// This file handles the asset and vendor files bundled into the executable.
+// The tool no longer depends on these directories being present next to the executable.
+package main
+
+import (
+	"embed"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+const assetDirName = "linkitall_assets"
+const vendorDirName = "linkitall_vendor"
+
+// Path to the leader-line bundle inside the vendor directory
+const leaderLineVendorPath = "leader-line/leader-line-v1.1.5.min.js"
+
+// linkitall_devserver holds files used only by the development server (never extracted)
+// linkitall_templates holds the starter graphs for the init subcommand
+//
+//go:embed linkitall_assets linkitall_vendor linkitall_devserver linkitall_templates
+var embeddedFiles embed.FS
+
+// Read a file from the embedded assets directory (eg: "template.html")
+func readEmbeddedAssetFile(name string) ([]byte, error) {
+	return embeddedFiles.ReadFile(path.Join(assetDirName, name))
+}
+
+// Read a file from the embedded vendor directory
+func readEmbeddedVendorFile(name string) ([]byte, error) {
+	return embeddedFiles.ReadFile(path.Join(vendorDirName, name))
+}
+
+// Write the embedded directory `dirName` (and everything inside it) to targetDir/dirName.
+func extractEmbeddedDir(dirName string, targetDir string) error {
+	return fs.WalkDir(embeddedFiles, dirName, func(srcPath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		targetPath := filepath.Join(targetDir, filepath.FromSlash(srcPath))
+		if entry.IsDir() {
+			return os.MkdirAll(targetPath, 0755)
+		}
+
+		data, err := embeddedFiles.ReadFile(srcPath)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(targetPath, data, 0644)
+	})
+}
+
+// Extract the embedded directory `dirName` to targetDir, unless it is already there.
+// With overwrite=true, the existing files are replaced with the embedded ones.
+func extractEmbeddedDirIfRequired(dirName string, targetDir string, overwrite bool) error {
+	targetPath := filepath.Join(targetDir, dirName)
+	if !overwrite && isPathAccessible(targetPath, "dir") {
+		log.Printf("Dir %s already exists. Skipping extraction\n", targetPath)
+		return nil
+	}
+
+	log.Printf("Extract %s -> %s\n", dirName, targetPath)
+	return extractEmbeddedDir(dirName, targetDir)
+}
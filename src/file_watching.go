@@ -0,0 +1,112 @@
+// This file implements the file watcher used in serve mode to rebuild the output automatically.
+// The watcher is polling based (instead of using OS notifications). It is simple, needs no extra
+// dependency, and works for mounted volumes and editors that replace the file on save.
+package main
+
+import (
+	"log"
+	"os"
+	"sort"
+	"time"
+)
+
+// How often the watched files are checked for changes
+const watchPollInterval = 500 * time.Millisecond
+
+// Changes are handled only after the files stay unchanged for this long. This avoids multiple
+// rebuilds when an editor writes a file in multiple steps, or when multiple files are saved.
+const watchDebounceDelay = 300 * time.Millisecond
+
+// State of a single watched file. A missing file is also a valid state.
+type watchedFileState struct {
+	Exists  bool
+	Size    int64
+	ModTime time.Time
+}
+
+// Get the state of all the given files
+func takeWatchSnapshot(paths []string) map[string]watchedFileState {
+	snapshot := make(map[string]watchedFileState, len(paths))
+	for _, path := range paths {
+		stat, err := os.Stat(path)
+		if err != nil {
+			snapshot[path] = watchedFileState{}
+			continue
+		}
+		snapshot[path] = watchedFileState{true, stat.Size(), stat.ModTime()}
+	}
+	return snapshot
+}
+
+// Check if two snapshots are the same
+func isSameWatchSnapshot(first map[string]watchedFileState,
+	second map[string]watchedFileState) bool {
+	if len(first) != len(second) {
+		return false
+	}
+	for path, state := range first {
+		otherState, ok := second[path]
+		if !ok || otherState.Exists != state.Exists || otherState.Size != state.Size ||
+			!otherState.ModTime.Equal(state.ModTime) {
+			return false
+		}
+	}
+	return true
+}
+
+// Return the files to be watched for the given args: the graph file, the included files and all
+// the local files referred in the resources section. If the graph cannot be loaded, the
+// previously watched files are watched (the files needing a fix are usually among them).
+func getWatchedFiles(args *CliArgs, previous []string) []string {
+	paths := []string{args.GraphFile}
+	gdfData, _, err := loadGdf(args.GraphFile)
+	if err != nil {
+		paths = append(paths, previous...)
+		return getUnique(paths)
+	}
+
+	paths = append(paths, gdfData.sourceFiles...)
+
+	for _, link := range gdfData.ResourceConfig {
+		if isLocalResource(link) {
+			pushBack(&paths, getLocalResourceFilePath(args.InputDir, link))
+		}
+	}
+	paths = getUnique(paths)
+	sort.Strings(paths)
+	return paths
+}
+
+// Watch the files returned by getPaths() and call onChange() after they change.
+// getPaths() is called again before every onChange(), since the list of files may change with
+// the graph. The files are checked against the snapshot taken before onChange(), so a change
+// saved while onChange() runs triggers another call. This function never returns.
+func watchFiles(getPaths func() []string, onChange func()) {
+	paths := getPaths()
+	log.Printf("Watching %d files for changes\n", len(paths))
+	snapshot := takeWatchSnapshot(paths)
+	pending := false
+	lastChangeTime := time.Now()
+
+	for {
+		time.Sleep(watchPollInterval)
+
+		newSnapshot := takeWatchSnapshot(paths)
+		if !isSameWatchSnapshot(snapshot, newSnapshot) {
+			snapshot = newSnapshot
+			pending = true
+			lastChangeTime = time.Now()
+			continue
+		}
+
+		if !pending || time.Since(lastChangeTime) < watchDebounceDelay {
+			continue
+		}
+
+		pending = false
+		paths = getPaths()
+		snapshot = takeWatchSnapshot(paths)
+		log.Printf("Change detected. Updating output\n")
+		onChange()
+	}
+}
@@ -0,0 +1,210 @@
+// This file handles the reporting of problems found in the Graph Definition File (GDF).
+// Every problem is reported with the position (file:line:col) of the offending item, and all the
+// problems found are reported together.
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const severityError = "error"
+const severityWarning = "warning"
+
+// Matches the line number in the errors reported by the YAML parser (eg: "line 12: ...")
+var yaml_line_pattern = regexp.MustCompile(`line (\d+): `)
+
+// Position of an item in a GDF file. Line and Col start from 1. Zero means unknown.
+type SourcePos struct {
+	File string
+	Line int
+	Col  int
+}
+
+func (pos SourcePos) String() string {
+	if pos.Line == 0 {
+		return pos.File
+	}
+	if pos.Col == 0 {
+		return fmt.Sprintf("%s:%d", pos.File, pos.Line)
+	}
+	return fmt.Sprintf("%s:%d:%d", pos.File, pos.Line, pos.Col)
+}
+
+// A single problem found in the GDF
+type Diagnostic struct {
+	// severityError or severityWarning
+	Severity string
+	// Position of the offending item
+	Pos SourcePos
+	// Name of the node involved (if any)
+	Node string
+	// Description of the problem
+	Message string
+}
+
+func (diag Diagnostic) Error() string {
+	if len(diag.Pos.File) == 0 {
+		return diag.Message
+	}
+	return fmt.Sprintf("%s: %s", diag.Pos, diag.Message)
+}
+
+// All the problems found in the GDF. Used as an error when it has at least one item.
+type DiagnosticList []Diagnostic
+
+func (list DiagnosticList) Error() string {
+	messages := make([]string, 0, len(list))
+	for _, diag := range list {
+		pushBack(&messages, diag.Error())
+	}
+	return strings.Join(messages, "\n")
+}
+
+// Add an error to the list. Message is formatted like fmt.Sprintf.
+func (list *DiagnosticList) addError(pos SourcePos, node string, format string, args ...any) {
+	*list = append(*list, Diagnostic{severityError, pos, node, fmt.Sprintf(format, args...)})
+}
+
+// Add a warning to the list. Message is formatted like fmt.Sprintf.
+func (list *DiagnosticList) addWarning(pos SourcePos, node string, format string, args ...any) {
+	*list = append(*list, Diagnostic{severityWarning, pos, node, fmt.Sprintf(format, args...)})
+}
+
+// Add all the items from the error (if it is a diagnostic or a list of them).
+// Any other error is added with the given position.
+func (list *DiagnosticList) addFromError(pos SourcePos, err error) {
+	switch value := err.(type) {
+	case nil:
+		return
+	case DiagnosticList:
+		*list = append(*list, value...)
+	case Diagnostic:
+		*list = append(*list, value)
+	default:
+		// Layout errors can be wrapped. The node is kept, along with the full message.
+		var layoutErr *LayoutError
+		if errors.As(err, &layoutErr) {
+			list.addError(pos, layoutErr.Node, "%s", err)
+			return
+		}
+		list.addError(pos, "", "%s", err)
+	}
+}
+
+// Return the list as an error. Returns nil if there are no errors in the list.
+func (list DiagnosticList) asError() error {
+	for _, diag := range list {
+		if diag.Severity == severityError {
+			return list
+		}
+	}
+	return nil
+}
+
+// Convert the errors from the YAML parser to diagnostics. The parser reports the line number
+// inside the message. That is moved to the position of the diagnostic.
+func diagnosticsFromYamlError(filename string, err error) DiagnosticList {
+	var messages []string
+	if typeErr, ok := err.(*yaml.TypeError); ok {
+		messages = typeErr.Errors
+	} else {
+		messages = []string{strings.TrimPrefix(err.Error(), "yaml: ")}
+	}
+
+	diags := DiagnosticList{}
+	for _, message := range messages {
+		pos := SourcePos{File: filename}
+		match := yaml_line_pattern.FindStringSubmatchIndex(message)
+		if match != nil {
+			pos.Line, _ = strconv.Atoi(message[match[2]:match[3]])
+			message = message[:match[0]] + message[match[1]:]
+		}
+		diags.addError(pos, "", "%s", message)
+	}
+	return diags
+}
+
+// Finds the positions of items in the YAML document of a GDF file
+type gdfLocator struct {
+	file string
+	// The top level node of the document (nil if the document is empty)
+	root *yaml.Node
+}
+
+// Create locator by parsing the YAML data again (as a tree of yaml.Node)
+func newGdfLocator(filename string, fileData []byte) *gdfLocator {
+	locator := &gdfLocator{file: filename}
+	var document yaml.Node
+	err := yaml.Unmarshal(fileData, &document)
+	if err == nil && len(document.Content) > 0 {
+		locator.root = document.Content[0]
+	}
+	return locator
+}
+
+func (locator *gdfLocator) posOf(node *yaml.Node) SourcePos {
+	return SourcePos{locator.file, node.Line, node.Column}
+}
+
+// Return the position of the item at the given path. Path items are mapping keys (string) or
+// sequence indices (int). If the last item is a mapping key, the position of the key is
+// returned. If the path is not fully available, the position of the deepest item found is
+// returned.
+func (locator *gdfLocator) find(path ...any) SourcePos {
+	if locator == nil {
+		return SourcePos{}
+	}
+	if locator.root == nil {
+		return SourcePos{File: locator.file}
+	}
+
+	current := locator.root
+	for pathIdx, item := range path {
+		var keyNode, valueNode *yaml.Node
+		switch key := item.(type) {
+		case string:
+			if current.Kind != yaml.MappingNode {
+				return locator.posOf(current)
+			}
+			for idx := 0; idx+1 < len(current.Content); idx += 2 {
+				if current.Content[idx].Value == key {
+					keyNode = current.Content[idx]
+					valueNode = current.Content[idx+1]
+					break
+				}
+			}
+		case int:
+			if current.Kind == yaml.SequenceNode && key >= 0 && key < len(current.Content) {
+				keyNode = current.Content[key]
+				valueNode = keyNode
+			}
+		}
+
+		if keyNode == nil {
+			return locator.posOf(current)
+		}
+		if pathIdx == len(path)-1 {
+			return locator.posOf(keyNode)
+		}
+		current = valueNode
+	}
+	return locator.posOf(current)
+}
+
+// Location of a node definition in the GDF (used for error reporting)
+type nodeSource struct {
+	locator *gdfLocator
+	// Index of the node in the nodes section of the file
+	index int
+}
+
+// Return the position of an item inside the node definition (see gdfLocator.find)
+func (source nodeSource) find(path ...any) SourcePos {
+	return source.locator.find(append([]any{"nodes", source.index}, path...)...)
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestAddFromErrorKeepsNodeOfWrappedLayoutError(t *testing.T) {
+	err := fmt.Errorf("unable to compute layout: %w",
+		newLayoutError("pure_water", layoutCheckLevelHints, "level too low"))
+
+	var diags DiagnosticList
+	diags.addFromError(SourcePos{File: "graph.yaml"}, err)
+	if len(diags) != 1 {
+		t.Fatalf("expected 1 diagnostic, got %v", diags)
+	}
+	if diags[0].Node != "pure_water" || diags[0].Severity != severityError {
+		t.Errorf("unexpected diagnostic: %+v", diags[0])
+	}
+	if diags[0].Message != err.Error() {
+		t.Errorf("message: got %q, want %q", diags[0].Message, err.Error())
+	}
+}
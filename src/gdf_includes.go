@@ -0,0 +1,165 @@
+// This file handles the `include` section of the GDF.
+// An included file can have its own nodes, resources and includes. Everything is merged into the
+// data of the main GDF. Node and resource names share a single namespace, so collisions are
+// reported with the files involved.
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Data loaded from an included file. Only these sections are allowed in such files.
+type GdfIncludeStruct struct {
+	Include        []string          `yaml:"include,omitempty"`
+	Nodes          []NodeInputFields `yaml:"nodes,omitempty"`
+	ResourceConfig ResourceConfigMap `yaml:"resources,omitempty"`
+}
+
+// Keeps track of the files loaded while resolving includes
+type includeResolver struct {
+	// Data of the main GDF. Nodes and resources from included files are added to it.
+	data *GdfDataStruct
+	// Dir of the main GDF. Resource paths are rewritten to be relative to this dir.
+	rootDir string
+	// Files in the current chain of includes (used to detect include cycles)
+	stack []string
+	// All the files loaded so far. A file included from multiple places is loaded only once.
+	loaded map[string]bool
+	diags  DiagnosticList
+}
+
+// Path of the file used in messages (relative to the main GDF if possible)
+func (resolver *includeResolver) displayPath(path string) string {
+	relPath, err := filepath.Rel(resolver.rootDir, path)
+	if err != nil {
+		return path
+	}
+	return filepath.ToSlash(relPath)
+}
+
+// Rewrite a resource link from an included file, so that it is relative to the main GDF.
+// Links other than relative local paths are returned as they are.
+func (resolver *includeResolver) rebaseResourceLink(includedFile string, link string) string {
+	filePart, suffix := splitResourceLink(link)
+	if !isLocalResource(link) || isAbsResourcePath(filePart) {
+		return link
+	}
+
+	fullPath := filepath.Join(filepath.Dir(includedFile), filepath.FromSlash(filePart))
+	relPath, err := filepath.Rel(resolver.rootDir, fullPath)
+	if err != nil {
+		return link
+	}
+	return filepath.ToSlash(relPath) + suffix
+}
+
+// Load all the files in the include list of a file (given by its locator).
+func (resolver *includeResolver) resolveIncludes(locator *gdfLocator, includes []string) {
+	for idx, includePath := range includes {
+		pos := locator.find("include", idx)
+		path := filepath.FromSlash(includePath)
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(filepath.Dir(locator.file), path)
+		}
+		absPath, err := filepath.Abs(path)
+		if err == nil {
+			path = absPath
+		}
+
+		for stackIdx, stackPath := range resolver.stack {
+			if stackPath != path {
+				continue
+			}
+			cycle := make([]string, 0, len(resolver.stack)-stackIdx+1)
+			for _, cyclePath := range resolver.stack[stackIdx:] {
+				pushBack(&cycle, resolver.displayPath(cyclePath))
+			}
+			pushBack(&cycle, resolver.displayPath(path))
+			resolver.diags.addError(pos, "", "include cycle: %s", strings.Join(cycle, " -> "))
+			break
+		}
+
+		if resolver.loaded[path] {
+			continue
+		}
+		resolver.loadIncludedFile(path, pos)
+	}
+}
+
+// Load a single included file and merge its nodes and resources into the main data.
+// `pos` is the position of the include entry (used for reporting errors).
+func (resolver *includeResolver) loadIncludedFile(path string, pos SourcePos) {
+	resolver.loaded[path] = true
+	fileData, err := os.ReadFile(path)
+	if err != nil {
+		resolver.diags.addError(pos, "", "unable to read included file: %s", err)
+		return
+	}
+	pushBack(&resolver.data.sourceFiles, path)
+
+	var included GdfIncludeStruct
+	decodeDiags, ok := decodeGdfYaml(path, fileData, &included)
+	resolver.diags = append(resolver.diags, decodeDiags...)
+	if !ok {
+		return
+	}
+
+	locator := newGdfLocator(path, fileData)
+	for idx := range included.Nodes {
+		node := included.Nodes[idx]
+		node.source = nodeSource{locator, idx}
+		pushBack(&resolver.data.Nodes, node)
+	}
+
+	data := resolver.data
+	// Sorted, so that the errors are reported in the same order every time
+	names := make([]string, 0, len(included.ResourceConfig))
+	for name := range included.ResourceConfig {
+		pushBack(&names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		link := included.ResourceConfig[name]
+		if firstLocator, found := data.resourceSources[name]; found {
+			resolver.diags.addError(locator.find("resources", name), "",
+				"resource name repeated '%v' (first defined at %v)",
+				name, firstLocator.find("resources", name))
+			continue
+		}
+		data.ResourceConfig[name] = resolver.rebaseResourceLink(path, link)
+		data.resourceSources[name] = locator
+	}
+
+	pushBack(&resolver.stack, path)
+	resolver.resolveIncludes(locator, included.Include)
+	resolver.stack = resolver.stack[:len(resolver.stack)-1]
+}
+
+// Load all the files included by the main GDF (recursively) and merge them into data.
+// Returns the problems found in the included files.
+func loadGdfIncludes(data *GdfDataStruct) DiagnosticList {
+	mainFile, err := filepath.Abs(data.locator.file)
+	if err != nil {
+		mainFile = data.locator.file
+	}
+	if data.ResourceConfig == nil {
+		data.ResourceConfig = ResourceConfigMap{}
+	}
+	data.resourceSources = make(map[string]*gdfLocator, len(data.ResourceConfig))
+	for name := range data.ResourceConfig {
+		data.resourceSources[name] = data.locator
+	}
+
+	resolver := includeResolver{
+		data:    data,
+		rootDir: filepath.Dir(mainFile),
+		stack:   []string{mainFile},
+		loaded:  map[string]bool{mainFile: true},
+	}
+	resolver.resolveIncludes(data.locator, data.Include)
+	return resolver.diags
+}
@@ -5,19 +5,28 @@
 package main
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"os"
 	"regexp"
+	"sort"
 	"strings"
 
 	"golang.org/x/text/cases"
 	"golang.org/x/text/language"
-	"gopkg.in/yaml.v2"
+	"gopkg.in/yaml.v3"
 )
 
 var name_pattern = regexp.MustCompile(`^[a-zA-Z0-9_]+$`)
 var importance_pattern = regexp.MustCompile(`^(lowest|lower|low|normal|high|higher|highest)$`)
+var edge_type_pattern = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+var edge_style_pattern = regexp.MustCompile(`^(solid|dashed|dotted)$`)
+var group_name_pattern = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// Colors allowed for groups: hex (#rgb, #rrggbb, ...), names and rgb()/hsl() forms
+var color_pattern = regexp.MustCompile(
+	`^(#[0-9a-fA-F]{3,8}|[a-zA-Z]+|(rgb|rgba|hsl|hsla)\([0-9., %]+\))$`)
 
 // Used in the <head> of the final HTML
 type HeadConfigFields struct {
@@ -34,6 +43,15 @@ type DisplayConfigFields struct {
 	VerticalStepPx int `yaml:"vertical-step-px,omitempty"`
 	// Width of the node box
 	NodeBoxWidthPx int `yaml:"node-box-width-px,omitempty"`
+	// Height of the node box (including the link panels)
+	NodeBoxHeightPx int `yaml:"node-box-height-px,omitempty"`
+	// How the height of nodes is decided:
+	// fixed: all nodes use node-box-height-px (default)
+	// auto: estimated from the title and subtitle of the node, at least node-box-height-px
+	NodeHeight string `yaml:"node-height,omitempty"`
+	// Gap between the disconnected parts of the graph (algo-config component-layout).
+	// A pointer, since 0 is a valid gap.
+	ComponentGapPx *int `yaml:"component-gap-px,omitempty"`
 }
 
 type LinkToFields struct {
@@ -45,6 +63,43 @@ type LinkToFields struct {
 
 type ResourceConfigMap map[string]string
 
+// A single entry in the depends-on list of a node. In the GDF, it can be just the name of the
+// node, or an object with the name and additional fields for the link.
+type DependencyFields struct {
+	// Name of the node depended on
+	Name string `yaml:"name"`
+	// Text shown on the link
+	Label string `yaml:"label,omitempty"`
+	// Kind of dependency (eg: requires, motivates, example-of). Used for coloring the link.
+	Type string `yaml:"type,omitempty"`
+	// Line style of the link: solid, dashed, dotted (default depends on the type)
+	Style string `yaml:"style,omitempty"`
+}
+
+// Allows the dependency to be given as a plain string (the name of the node)
+func (dep *DependencyFields) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*dep = DependencyFields{Name: value.Value}
+		return nil
+	}
+
+	// Decoding with value.Decode() does not report unknown fields. Check them here.
+	if value.Kind == yaml.MappingNode {
+		knownFields := map[string]bool{"name": true, "label": true, "type": true, "style": true}
+		for idx := 0; idx+1 < len(value.Content); idx += 2 {
+			keyNode := value.Content[idx]
+			if !knownFields[keyNode.Value] {
+				return &yaml.TypeError{Errors: []string{fmt.Sprintf(
+					"line %d: field %s not found in depends-on entry", keyNode.Line, keyNode.Value)}}
+			}
+		}
+	}
+
+	// A different type is required to avoid calling this function again
+	type plainDependencyFields DependencyFields
+	return value.Decode((*plainDependencyFields)(dep))
+}
+
 // Defines the node definition by the user in the
 type NodeInputFields struct {
 	// A unique name for the node (no spaces, all small letters)
@@ -56,51 +111,177 @@ type NodeInputFields struct {
 	// Importance to be assigned to this node. It is a 7 point scale:
 	// lowest, lower, low, normal, high, higher, highest
 	Importance string `yaml:"importance,omitempty"`
-	// List of node names (current node depends on these nodes)
-	DependsOn []string `yaml:"depends-on,omitempty"`
+	// List of nodes (current node depends on these nodes)
+	DependsOn []DependencyFields `yaml:"depends-on,omitempty"`
 	// Link to the resource
 	LinkTo LinkToFields `yaml:"linkto,omitempty"`
+	// Level for the node, overriding the level strategy (optional). Level 0 has the nodes
+	// without dependencies.
+	Level *int `yaml:"level,omitempty"`
+	// Position of the node within its level (optional). 0 is the first position.
+	Shift *int `yaml:"shift,omitempty"`
+	// Name of a node which must be in the same level as this node (optional)
+	SameLevelAs string `yaml:"same-level-as,omitempty"`
+	// Group of the node (optional). Nodes of a group are kept together.
+	Group string `yaml:"group,omitempty"`
+	// Location of the node in the GDF (not part of the YAML)
+	source nodeSource
 }
 
 type AlgoConfigFields struct {
 	LevelStrategy  string `yaml:"level-strategy,omitempty"`
 	ArrowDirection string `yaml:"arrow-direction,omitempty"`
 	NodeSorting    string `yaml:"node-sorting,omitempty"`
+	// Order of nodes within a level: declaration, barycenter, median
+	NodeOrdering string `yaml:"node-ordering,omitempty"`
+	// Drawing of links spanning multiple levels: direct, waypoints
+	LinkRouting string `yaml:"link-routing,omitempty"`
+	// Horizontal position of nodes within a level: spread, balanced
+	HorizontalPlacement string `yaml:"horizontal-placement,omitempty"`
+	// Direction in which the levels are stacked: vertical, horizontal (or left2right),
+	// right2left
+	Orientation string `yaml:"orientation,omitempty"`
+	// Max number of nodes in a level for level-strategy: coffman-graham (0 means no limit)
+	MaxNodesPerLevel int `yaml:"max-nodes-per-level,omitempty"`
+	// Skip the dependencies that are already reached through other dependencies of the node
+	TransitiveReduction bool `yaml:"transitive-reduction,omitempty"`
+	// Placement of the disconnected parts of the graph: mixed, side-by-side, grid
+	ComponentLayout string `yaml:"component-layout,omitempty"`
+	// Number of components in a row for component-layout: grid (0 means automatic)
+	ComponentGridColumns int `yaml:"component-grid-columns,omitempty"`
+}
+
+// Details of a group of nodes (all optional)
+type GroupFields struct {
+	// Label shown for the group (default is based on the name)
+	Label string `yaml:"label,omitempty"`
+	// Color of the region of the group (any CSS color)
+	Color string `yaml:"color,omitempty"`
 }
 
 type GdfDataStruct struct {
+	// Other GDF files with more nodes and resources (paths relative to this file)
+	Include        []string            `yaml:"include,omitempty"`
 	Nodes          []NodeInputFields   `yaml:"nodes"`
 	HeadConfig     HeadConfigFields    `yaml:"head-config"`
 	DisplayConfig  DisplayConfigFields `yaml:"display-config,omitempty"`
 	ResourceConfig ResourceConfigMap   `yaml:"resources"`
 	AlgoConfig     AlgoConfigFields    `yaml:"algo-config,omitempty"`
+	// Groups of nodes (name -> details). Groups used by the nodes are added if not given.
+	Groups map[string]GroupFields `yaml:"groups,omitempty"`
+	// Used to find positions of items in the GDF (not part of the YAML)
+	locator *gdfLocator
+	// Locator of the file where each resource is defined
+	resourceSources map[string]*gdfLocator
+	// All the files loaded for this GDF (main file and included files)
+	sourceFiles []string
+	// Problems found in the GDF that do not stop the processing
+	warnings DiagnosticList
 }
 
-func validateAndUpdateAlgoConfig(algoConfig *AlgoConfigFields) error {
+// Validate algo-config. Default values are filled for the fields not given.
+func validateAndUpdateAlgoConfig(algoConfig *AlgoConfigFields, locator *gdfLocator) error {
+	var diags DiagnosticList
 	if len(algoConfig.LevelStrategy) == 0 {
 		algoConfig.LevelStrategy = "bottom2top"
 	}
-	if algoConfig.LevelStrategy != "bottom2top" && algoConfig.LevelStrategy != "top2bottom" {
-		return fmt.Errorf("invalid level strategy: '%v'", algoConfig.LevelStrategy)
+	if algoConfig.LevelStrategy != "bottom2top" && algoConfig.LevelStrategy != "top2bottom" &&
+		algoConfig.LevelStrategy != "coffman-graham" {
+		diags.addError(locator.find("algo-config", "level-strategy"), "",
+			"invalid level strategy: '%v'", algoConfig.LevelStrategy)
+	}
+
+	if algoConfig.MaxNodesPerLevel < 0 {
+		diags.addError(locator.find("algo-config", "max-nodes-per-level"), "",
+			"invalid max nodes per level: %v", algoConfig.MaxNodesPerLevel)
+	} else if algoConfig.MaxNodesPerLevel > 0 && algoConfig.LevelStrategy != "coffman-graham" {
+		diags.addError(locator.find("algo-config", "max-nodes-per-level"), "",
+			"max-nodes-per-level is only supported with level-strategy: coffman-graham")
 	}
 
 	if len(algoConfig.ArrowDirection) == 0 {
 		algoConfig.ArrowDirection = "child2parent"
 	}
 	if algoConfig.ArrowDirection != "child2parent" && algoConfig.ArrowDirection != "parent2child" {
-		return fmt.Errorf("invalid arrow direction: '%v'", algoConfig.ArrowDirection)
+		diags.addError(locator.find("algo-config", "arrow-direction"), "",
+			"invalid arrow direction: '%v'", algoConfig.ArrowDirection)
 	}
 
 	if len(algoConfig.NodeSorting) == 0 {
 		algoConfig.NodeSorting = "ascend"
 	}
 	if algoConfig.NodeSorting != "ascend" && algoConfig.NodeSorting != "descend" {
-		return fmt.Errorf("invalid growth strategy: '%v'", algoConfig.NodeSorting)
+		diags.addError(locator.find("algo-config", "node-sorting"), "",
+			"invalid growth strategy: '%v'", algoConfig.NodeSorting)
+	}
+
+	if len(algoConfig.NodeOrdering) == 0 {
+		algoConfig.NodeOrdering = "declaration"
+	}
+	if algoConfig.NodeOrdering != "declaration" && algoConfig.NodeOrdering != "barycenter" &&
+		algoConfig.NodeOrdering != "median" {
+		diags.addError(locator.find("algo-config", "node-ordering"), "",
+			"invalid node ordering: '%v'", algoConfig.NodeOrdering)
+	}
+
+	if len(algoConfig.LinkRouting) == 0 {
+		algoConfig.LinkRouting = "direct"
+	}
+	if algoConfig.LinkRouting != "direct" && algoConfig.LinkRouting != "waypoints" {
+		diags.addError(locator.find("algo-config", "link-routing"), "",
+			"invalid link routing: '%v'", algoConfig.LinkRouting)
+	}
+
+	if len(algoConfig.HorizontalPlacement) == 0 {
+		algoConfig.HorizontalPlacement = "spread"
+	}
+	if algoConfig.HorizontalPlacement != "spread" && algoConfig.HorizontalPlacement != "balanced" {
+		diags.addError(locator.find("algo-config", "horizontal-placement"), "",
+			"invalid horizontal placement: '%v'", algoConfig.HorizontalPlacement)
 	}
-	return nil
+
+	if len(algoConfig.Orientation) == 0 {
+		algoConfig.Orientation = "vertical"
+	}
+	switch algoConfig.Orientation {
+	case "vertical", "horizontal":
+	case "left2right":
+		algoConfig.Orientation = "horizontal"
+	case "right2left":
+		// Same as horizontal with the levels in the reverse order. The levels are reversed
+		// like node-sorting: descend does (combined with descend, this gives left to right).
+		algoConfig.Orientation = "horizontal"
+		if algoConfig.NodeSorting == "descend" {
+			algoConfig.NodeSorting = "ascend"
+		} else {
+			algoConfig.NodeSorting = "descend"
+		}
+	default:
+		diags.addError(locator.find("algo-config", "orientation"), "",
+			"invalid orientation: '%v'", algoConfig.Orientation)
+	}
+
+	if len(algoConfig.ComponentLayout) == 0 {
+		algoConfig.ComponentLayout = "mixed"
+	}
+	if algoConfig.ComponentLayout != "mixed" && algoConfig.ComponentLayout != "side-by-side" &&
+		algoConfig.ComponentLayout != "grid" {
+		diags.addError(locator.find("algo-config", "component-layout"), "",
+			"invalid component layout: '%v'", algoConfig.ComponentLayout)
+	}
+	if algoConfig.ComponentGridColumns < 0 {
+		diags.addError(locator.find("algo-config", "component-grid-columns"), "",
+			"invalid component grid columns: %v", algoConfig.ComponentGridColumns)
+	} else if algoConfig.ComponentGridColumns > 0 && algoConfig.ComponentLayout != "grid" {
+		diags.addError(locator.find("algo-config", "component-grid-columns"), "",
+			"component-grid-columns is only supported with component-layout: grid")
+	}
+	return diags.asError()
 }
 
-func validateAndUpdateDisplayConfig(displayConfig *DisplayConfigFields) error {
+func validateAndUpdateDisplayConfig(displayConfig *DisplayConfigFields,
+	locator *gdfLocator) error {
+	var diags DiagnosticList
 	if displayConfig.HorizontalStepPx == 0 {
 		displayConfig.HorizontalStepPx = 400
 	}
@@ -110,7 +291,30 @@ func validateAndUpdateDisplayConfig(displayConfig *DisplayConfigFields) error {
 	if displayConfig.NodeBoxWidthPx == 0 {
 		displayConfig.NodeBoxWidthPx = 300
 	}
-	return nil
+	if displayConfig.NodeBoxHeightPx == 0 {
+		displayConfig.NodeBoxHeightPx = 150
+	}
+	if displayConfig.ComponentGapPx == nil {
+		defaultGapPx := 200
+		displayConfig.ComponentGapPx = &defaultGapPx
+	}
+	if *displayConfig.ComponentGapPx < 0 {
+		diags.addError(locator.find("display-config", "component-gap-px"), "",
+			"invalid component gap: %v", *displayConfig.ComponentGapPx)
+	}
+	if displayConfig.NodeBoxHeightPx < minNodeBoxHeightPx {
+		diags.addError(locator.find("display-config", "node-box-height-px"), "",
+			"node-box-height-px must be at least %v: %v", minNodeBoxHeightPx,
+			displayConfig.NodeBoxHeightPx)
+	}
+	if len(displayConfig.NodeHeight) == 0 {
+		displayConfig.NodeHeight = "fixed"
+	}
+	if displayConfig.NodeHeight != "fixed" && displayConfig.NodeHeight != "auto" {
+		diags.addError(locator.find("display-config", "node-height"), "",
+			"invalid node-height: '%v'", displayConfig.NodeHeight)
+	}
+	return diags.asError()
 }
 
 // Replace underscores with spaces and capitalize first letter of every word.
@@ -124,32 +328,38 @@ func convertNameToTitle(name string) string {
 
 // Validate data related to nodes in GDF
 // This function changes blank ("") value for node.Importance to "normal".
+// All the problems found are returned together (as DiagnosticList).
 func validateAndUpdateNodes(nodes []NodeInputFields) error {
+	var diags DiagnosticList
 	// Number of nodes without any dependencies (level 0 nodes)
 	numLevel0Nodes := 0
-	// Unique nodes (names)
-	uniqueNames := map[string]bool{}
+	// Unique nodes (names) -> index of the node
+	uniqueNames := map[string]int{}
 	for idx := range nodes {
 		node := &nodes[idx]
 
 		// CHECK: node name must be [a-zA-Z0-9_]
 		if !name_pattern.MatchString(node.Name) {
-			return fmt.Errorf("invalid node name (only letters, numbers, _) '%v'", node.Name)
+			diags.addError(node.source.find("name"), node.Name,
+				"invalid node name (only letters, numbers, _) '%v'", node.Name)
 		}
 
 		// CHECK: node name must be unique
-		if _, ok := uniqueNames[node.Name]; ok {
-			return fmt.Errorf("node name repeated '%v'", node.Name)
+		if firstIdx, ok := uniqueNames[node.Name]; ok {
+			diags.addError(node.source.find("name"), node.Name,
+				"node name repeated '%v' (first defined at %v)",
+				node.Name, nodes[firstIdx].source.find("name"))
+		} else {
+			uniqueNames[node.Name] = idx
 		}
-		uniqueNames[node.Name] = true
 
 		if node.Importance == "" {
 			node.Importance = "normal"
 		}
 		// CHECK: importance must be one of the 7 options
 		if !importance_pattern.MatchString(node.Importance) {
-			return fmt.Errorf("unknown importance pattern for node '%v': '%v'",
-				node.Name, node.Importance)
+			diags.addError(node.source.find("importance"), node.Name,
+				"unknown importance pattern for node '%v': '%v'", node.Name, node.Importance)
 		}
 
 		if len(node.DependsOn) == 0 {
@@ -157,32 +367,99 @@ func validateAndUpdateNodes(nodes []NodeInputFields) error {
 			numLevel0Nodes += 1
 		}
 
+		// CHECK: level and shift hints can not be negative
+		if node.Level != nil && *node.Level < 0 {
+			diags.addError(node.source.find("level"), node.Name,
+				"invalid level for node '%v': %v", node.Name, *node.Level)
+		}
+		if node.Shift != nil && *node.Shift < 0 {
+			diags.addError(node.source.find("shift"), node.Name,
+				"invalid shift for node '%v': %v", node.Name, *node.Shift)
+		}
+
 		// If the title is not given, fill it using the name.
 		if len(node.Title) == 0 {
 			node.Title = convertNameToTitle(node.Name)
 		}
 	}
 
-	if numLevel0Nodes == 0 {
-		return fmt.Errorf("there must be atleast 1 node without any dependency")
-	}
-
 	for _, node := range nodes {
-		for _, dep := range node.DependsOn {
+		// CHECK: same-level-as must be one of the other node names
+		if len(node.SameLevelAs) > 0 {
+			if _, ok := uniqueNames[node.SameLevelAs]; !ok {
+				diags.addError(node.source.find("same-level-as"), node.Name,
+					"unknown node in same-level-as for node '%v': '%v'",
+					node.Name, node.SameLevelAs)
+			} else if node.SameLevelAs == node.Name {
+				diags.addError(node.source.find("same-level-as"), node.Name,
+					"node '%v' can not be same-level-as itself", node.Name)
+			}
+		}
+
+		for depIdx, dep := range node.DependsOn {
 			// CHECK: dependency must be one of the node names
-			if _, ok := uniqueNames[dep]; !ok {
-				return fmt.Errorf("unknown dependency for node '%v': '%v'", node.Name, dep)
+			if _, ok := uniqueNames[dep.Name]; !ok {
+				diags.addError(node.source.find("depends-on", depIdx), node.Name,
+					"unknown dependency for node '%v': '%v'", node.Name, dep.Name)
+			}
+
+			// CHECK: type and style of the link (both optional)
+			if len(dep.Type) > 0 && !edge_type_pattern.MatchString(dep.Type) {
+				diags.addError(node.source.find("depends-on", depIdx, "type"), node.Name,
+					"invalid dependency type (only letters, numbers, _, -) '%v'", dep.Type)
+			}
+			if len(dep.Style) > 0 && !edge_style_pattern.MatchString(dep.Style) {
+				diags.addError(node.source.find("depends-on", depIdx, "style"), node.Name,
+					"invalid dependency style (solid, dashed, dotted) '%v'", dep.Style)
 			}
 		}
 	}
 
-	return nil
+	// Cycle detection needs valid names and dependencies
+	if len(diags) > 0 {
+		return diags
+	}
+
+	// CHECK: there must not be any dependency cycle. All the cycles are reported together.
+	for _, diag := range findDependencyCycles(nodes) {
+		diag.Pos = nodes[uniqueNames[diag.Node]].source.find("name")
+		diags = append(diags, diag)
+	}
+	if len(diags) > 0 {
+		return diags
+	}
+
+	// CHECK: level hints (level, same-level-as) must agree with the dependencies
+	if hasLevelHints(nodes) {
+		_, hintDiags := computeLevelHintBounds(nodes)
+		for _, diag := range hintDiags {
+			node := &nodes[uniqueNames[diag.Node]]
+			diag.Pos = node.source.find("name")
+			if node.Level != nil {
+				diag.Pos = node.source.find("level")
+			} else if len(node.SameLevelAs) > 0 {
+				diag.Pos = node.source.find("same-level-as")
+			}
+			diags = append(diags, diag)
+		}
+		if len(diags) > 0 {
+			return diags
+		}
+	}
+
+	// Without cycles, this should never happen. Still, it is better to keep this check here.
+	if numLevel0Nodes == 0 {
+		diags.addError(SourcePos{}, "", "there must be atleast 1 node without any dependency")
+	}
+
+	return diags.asError()
 }
 
 // Goes over each source in resources and makes sure the input is proper.
 // Also iterates over the nodes and makes sure all the resources are available.
 // TODO: check if the specified resource file actually exists!
 func validateAndUpdateResources(resources ResourceConfigMap, nodes []NodeInputFields) error {
+	var diags DiagnosticList
 	// Check all nodes are using resources actually present in the GDF
 	for idx := range nodes {
 		node := &nodes[idx]
@@ -191,41 +468,104 @@ func validateAndUpdateResources(resources ResourceConfigMap, nodes []NodeInputFi
 		}
 		_, ok := resources[node.LinkTo.ResourceName]
 		if !ok {
-			return fmt.Errorf("error in node %s: linkto resource %s not found",
+			diags.addError(node.source.find("linkto", "resource"), node.Name,
+				"error in node %s: linkto resource %s not found",
 				node.Name, node.LinkTo.ResourceName)
 		}
 	}
 
-	return nil
+	return diags.asError()
 }
 
-// Validate graph data loaded from YAML
-// Input must not be nil.
-func validateAndUpdateGraphData(data *GdfDataStruct) error {
-	err := validateAndUpdateNodes(data.Nodes)
-	if err != nil {
-		return err
+// Validate the groups section and the groups of the nodes. If the groups section is given,
+// all the groups used must be there. Otherwise, groups are created for the names used.
+// Labels of the groups are filled if not given.
+func validateAndUpdateGroups(data *GdfDataStruct) error {
+	var diags DiagnosticList
+	groupsGiven := len(data.Groups) > 0
+	if data.Groups == nil {
+		data.Groups = map[string]GroupFields{}
 	}
 
-	err = validateAndUpdateDisplayConfig(&data.DisplayConfig)
-	if err != nil {
-		return err
+	// Sorted, so that the errors are reported in the same order every time
+	names := make([]string, 0, len(data.Groups))
+	for name := range data.Groups {
+		pushBack(&names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		group := data.Groups[name]
+		if !group_name_pattern.MatchString(name) {
+			diags.addError(data.locator.find("groups", name), "",
+				"invalid group name (only letters, numbers, _, -) '%v'", name)
+		}
+		if len(group.Color) > 0 && !color_pattern.MatchString(group.Color) {
+			diags.addError(data.locator.find("groups", name, "color"), "",
+				"invalid color for group '%v': '%v'", name, group.Color)
+		}
 	}
 
-	err = validateAndUpdateAlgoConfig(&data.AlgoConfig)
-	if err != nil {
-		return err
+	for _, node := range data.Nodes {
+		if len(node.Group) == 0 {
+			continue
+		}
+		if _, found := data.Groups[node.Group]; found {
+			continue
+		}
+		if groupsGiven {
+			diags.addError(node.source.find("group"), node.Name,
+				"unknown group for node '%v': '%v'", node.Name, node.Group)
+		} else if !group_name_pattern.MatchString(node.Group) {
+			diags.addError(node.source.find("group"), node.Name,
+				"invalid group name (only letters, numbers, _, -) '%v'", node.Group)
+		} else {
+			data.Groups[node.Group] = GroupFields{}
+		}
 	}
 
-	err = validateAndUpdateResources(data.ResourceConfig, data.Nodes)
-	if err != nil {
-		return err
+	for name, group := range data.Groups {
+		if len(group.Label) == 0 {
+			group.Label = convertNameToTitle(name)
+			data.Groups[name] = group
+		}
 	}
+	return diags.asError()
+}
 
-	return nil
+// Validate graph data loaded from YAML
+// Input must not be nil.
+// All the validation steps are run, and the problems found are returned together.
+func validateAndUpdateGraphData(data *GdfDataStruct) error {
+	var diags DiagnosticList
+	filePos := data.locator.find()
+
+	diags.addFromError(filePos, validateAndUpdateNodes(data.Nodes))
+	diags.addFromError(filePos, validateAndUpdateDisplayConfig(&data.DisplayConfig, data.locator))
+	diags.addFromError(filePos, validateAndUpdateAlgoConfig(&data.AlgoConfig, data.locator))
+	diags.addFromError(filePos, validateAndUpdateResources(data.ResourceConfig, data.Nodes))
+	diags.addFromError(filePos, validateAndUpdateGroups(data))
+
+	return diags.asError()
+}
+
+// Decode YAML data of a GDF file to `out`. Unknown fields are reported as errors.
+// Type errors (like unknown fields) do not stop the decoding. Anything else (like syntax errors)
+// does. Returns the problems found and whether the decoding succeeded.
+func decodeGdfYaml(filename string, fileData []byte, out any) (DiagnosticList, bool) {
+	decoder := yaml.NewDecoder(bytes.NewReader(fileData))
+	// Report unknown fields (usually typos)
+	decoder.KnownFields(true)
+	err := decoder.Decode(out)
+	if _, ok := err.(*yaml.TypeError); ok {
+		return diagnosticsFromYamlError(filename, err), true
+	} else if err != nil && err != io.EOF {
+		return diagnosticsFromYamlError(filename, err), false
+	}
+	return nil, true
 }
 
 // Load Graph Definition File
+// Files in the include section are loaded as well.
 //
 // Inputs:
 // filename - input filename (YAML file for GDF)
@@ -233,7 +573,7 @@ func validateAndUpdateGraphData(data *GdfDataStruct) error {
 // Returns: (data, readable, error)
 // data - loaded data (if everything goes fine)
 // readable - true if file is readable
-// error - error if any
+// error - error if any. Problems in the GDF are reported as DiagnosticList.
 func loadGdf(filename string) (*GdfDataStruct, bool, error) {
 	file, err := os.Open(filename)
 	if err != nil {
@@ -247,15 +587,29 @@ func loadGdf(filename string) (*GdfDataStruct, bool, error) {
 	}
 
 	var data GdfDataStruct
-	err = yaml.UnmarshalStrict(fileData, &data)
-	if err != nil {
-		return nil, true, err
+	diags, ok := decodeGdfYaml(filename, fileData, &data)
+	if !ok {
+		return nil, true, diags
 	}
 
-	err = validateAndUpdateGraphData(&data)
+	data.locator = newGdfLocator(filename, fileData)
+	data.sourceFiles = []string{filename}
+	for idx := range data.Nodes {
+		data.Nodes[idx].source = nodeSource{data.locator, idx}
+	}
+	diags = append(diags, loadGdfIncludes(&data)...)
+
+	diags.addFromError(data.locator.find(), validateAndUpdateGraphData(&data))
+	err = diags.asError()
 	if err != nil {
 		return nil, true, err
 	}
 
+	for _, redundant := range findRedundantDependencies(data.Nodes) {
+		node := &data.Nodes[redundant.nodeIdx]
+		data.warnings.addWarning(node.source.find("depends-on", redundant.depIdx), node.Name,
+			"redundant dependency of node '%v': '%v' (already reached through '%v')",
+			node.Name, node.DependsOn[redundant.depIdx].Name, redundant.through)
+	}
 	return &data, true, nil
 }
@@ -3,10 +3,12 @@ module github.com/charstorm/linkitall/v2
 go 1.20
 
 require (
-	github.com/alexflint/go-arg v1.4.3 // indirect
+	github.com/alexflint/go-arg v1.4.3
+	golang.org/x/text v0.12.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
 	github.com/alexflint/go-scalar v1.1.0 // indirect
-	github.com/otiai10/copy v1.12.0 // indirect
 	golang.org/x/sys v0.5.0 // indirect
-	golang.org/x/text v0.12.0 // indirect
-	gopkg.in/yaml.v2 v2.4.0 // indirect
 )
@@ -0,0 +1,464 @@
+// This file contains graph algorithms that work directly on the node definitions from the GDF
+// (using node names). These are used for validating the graph before the actual computation.
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Return the names of all the nodes the given node depends on
+func getDependencyNames(node *NodeInputFields) []string {
+	names := make([]string, 0, len(node.DependsOn))
+	for _, dep := range node.DependsOn {
+		pushBack(&names, dep.Name)
+	}
+	return names
+}
+
+// State used by Tarjan's strongly connected components algorithm
+type sccSearchState struct {
+	// Dependencies of every node (node name -> dependency names)
+	edges map[string][]string
+	// Order in which nodes are visited
+	index map[string]int
+	// Smallest index reachable from the node
+	lowLink map[string]int
+	// Nodes in the current search path
+	stack   []string
+	onStack map[string]bool
+	// Result
+	components [][]string
+}
+
+// Visit a node (recursive part of Tarjan's algorithm)
+func (state *sccSearchState) visit(name string) {
+	state.index[name] = len(state.index)
+	state.lowLink[name] = state.index[name]
+	pushBack(&state.stack, name)
+	state.onStack[name] = true
+
+	for _, dep := range state.edges[name] {
+		if _, visited := state.index[dep]; !visited {
+			state.visit(dep)
+			if state.lowLink[dep] < state.lowLink[name] {
+				state.lowLink[name] = state.lowLink[dep]
+			}
+		} else if state.onStack[dep] && state.index[dep] < state.lowLink[name] {
+			state.lowLink[name] = state.index[dep]
+		}
+	}
+
+	if state.lowLink[name] != state.index[name] {
+		return
+	}
+
+	// name is the root of a component. Pop the component from the stack.
+	component := make([]string, 0, defaultCapacity)
+	for {
+		last := state.stack[len(state.stack)-1]
+		state.stack = state.stack[:len(state.stack)-1]
+		state.onStack[last] = false
+		pushBack(&component, last)
+		if last == name {
+			break
+		}
+	}
+	pushBack(&state.components, component)
+}
+
+// Find all the strongly connected components of the dependency graph.
+// Nodes are visited in the declaration order. Dependencies must be valid node names.
+func findStronglyConnectedComponents(nodes []NodeInputFields) [][]string {
+	state := sccSearchState{
+		edges:   make(map[string][]string, len(nodes)),
+		index:   map[string]int{},
+		lowLink: map[string]int{},
+		onStack: map[string]bool{},
+	}
+	for _, node := range nodes {
+		state.edges[node.Name] = getDependencyNames(&node)
+	}
+
+	for _, node := range nodes {
+		if _, visited := state.index[node.Name]; !visited {
+			state.visit(node.Name)
+		}
+	}
+	return state.components
+}
+
+// Find a cycle passing through `start` using only the nodes inside the component.
+// Returns the path of the cycle with start at both ends (eg: [a, b, c, a]).
+func findCycleInComponent(start string, edges map[string][]string,
+	inComponent map[string]bool) []string {
+	// Breadth first search from start. The shortest path back to start is the cycle.
+	parent := map[string]string{}
+	queue := []string{start}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, dep := range edges[current] {
+			if !inComponent[dep] {
+				continue
+			}
+			if dep == start {
+				// Walk back to start to build the path
+				path := []string{start}
+				for node := current; node != start; node = parent[node] {
+					pushBack(&path, node)
+				}
+				pushBack(&path, start)
+				// The path was built backwards
+				for ii, jj := 1, len(path)-2; ii < jj; ii, jj = ii+1, jj-1 {
+					path[ii], path[jj] = path[jj], path[ii]
+				}
+				return path
+			}
+			if _, seen := parent[dep]; !seen {
+				parent[dep] = current
+				pushBack(&queue, dep)
+			}
+		}
+	}
+	return nil
+}
+
+// Find dependency cycles in the nodes. For every strongly connected component with a cycle,
+// one cycle path is reported along with all the nodes in the component.
+// The diagnostics are for the first node of every cycle. Their positions are not filled.
+// Returns nil if there are no cycles. Dependencies must be valid node names.
+func findDependencyCycles(nodes []NodeInputFields) DiagnosticList {
+	edges := make(map[string][]string, len(nodes))
+	declOrder := make(map[string]int, len(nodes))
+	for idx, node := range nodes {
+		edges[node.Name] = getDependencyNames(&node)
+		declOrder[node.Name] = idx
+	}
+
+	components := findStronglyConnectedComponents(nodes)
+	// Report the cycles in the order of declaration of their first node
+	for _, component := range components {
+		sortByDeclOrder(component, declOrder)
+	}
+	sort.Slice(components, func(ii int, jj int) bool {
+		return declOrder[components[ii][0]] < declOrder[components[jj][0]]
+	})
+
+	var diags DiagnosticList
+	for _, component := range components {
+		if len(component) == 1 {
+			// Single node is a cycle only if it depends on itself
+			name := component[0]
+			for _, dep := range edges[name] {
+				if dep == name {
+					diags.addError(SourcePos{}, name, "dependency cycle: %s -> %s", name, name)
+					break
+				}
+			}
+			continue
+		}
+
+		// Start the cycle from the node declared first, to keep the report stable
+		inComponent := map[string]bool{}
+		for _, name := range component {
+			inComponent[name] = true
+		}
+
+		cycle := findCycleInComponent(component[0], edges, inComponent)
+		message := fmt.Sprintf("dependency cycle: %s", strings.Join(cycle, " -> "))
+		if len(cycle)-1 < len(component) {
+			// There are more nodes involved than the ones in the cycle shown
+			message += fmt.Sprintf(" (all %d nodes in this cycle group: %s)",
+				len(component), strings.Join(component, ", "))
+		}
+		diags.addError(SourcePos{}, component[0], "%s", message)
+	}
+
+	return diags
+}
+
+// Sort node names based on the order of declaration in the GDF
+func sortByDeclOrder(names []string, declOrder map[string]int) {
+	sort.Slice(names, func(ii int, jj int) bool {
+		return declOrder[names[ii]] < declOrder[names[jj]]
+	})
+}
+
+// A dependency which is also reached through another dependency of the same node
+type redundantDependency struct {
+	// Index of the node in the GDF nodes
+	nodeIdx int
+	// Index of the dependency in the depends-on list of the node
+	depIdx int
+	// The other dependency of the node which reaches the same dependency
+	through string
+}
+
+// Find the dependencies that are not needed in the transitive reduction of the dependency
+// graph. That is, dependencies reached through another dependency of the same node.
+// Dependencies must be valid node names and there must be no cycles.
+func findRedundantDependencies(nodes []NodeInputFields) []redundantDependency {
+	edges := make(map[string][]string, len(nodes))
+	for idx := range nodes {
+		edges[nodes[idx].Name] = getDependencyNames(&nodes[idx])
+	}
+
+	// All the nodes reachable from every node (excluding itself), filled as required
+	reachable := make(map[string]map[string]bool, len(nodes))
+	var getReachable func(name string) map[string]bool
+	getReachable = func(name string) map[string]bool {
+		if result, ok := reachable[name]; ok {
+			return result
+		}
+		result := map[string]bool{}
+		for _, dep := range edges[name] {
+			result[dep] = true
+			for other := range getReachable(dep) {
+				result[other] = true
+			}
+		}
+		reachable[name] = result
+		return result
+	}
+
+	result := make([]redundantDependency, 0)
+	for nodeIdx := range nodes {
+		deps := edges[nodes[nodeIdx].Name]
+		for depIdx, dep := range deps {
+			for _, other := range deps {
+				if other != dep && getReachable(other)[dep] {
+					pushBack(&result, redundantDependency{nodeIdx, depIdx, other})
+					break
+				}
+			}
+		}
+	}
+	return result
+}
+
+// Levels allowed for the nodes by the level hints (level and same-level-as fields).
+// Nodes linked by same-level-as form a group, and all the nodes in a group share the bounds.
+type levelHintBounds struct {
+	// Group of every node (name of the first node of the group in declaration order)
+	group map[string]string
+	// Min and max level allowed for every group
+	minLevel map[string]int
+	maxLevel map[string]int
+}
+
+// Used as the max level for groups without any limit
+const unboundedLevel = math.MaxInt32
+
+// Check if any node has level hints (level or same-level-as)
+func hasLevelHints(nodes []NodeInputFields) bool {
+	for _, node := range nodes {
+		if node.Level != nil || len(node.SameLevelAs) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Find the group of every node based on same-level-as (union-find)
+func findSameLevelGroups(nodes []NodeInputFields) map[string]string {
+	parent := make(map[string]string, len(nodes))
+	var findRoot func(name string) string
+	findRoot = func(name string) string {
+		if parent[name] == name {
+			return name
+		}
+		parent[name] = findRoot(parent[name])
+		return parent[name]
+	}
+
+	for _, node := range nodes {
+		parent[node.Name] = node.Name
+	}
+	for _, node := range nodes {
+		if len(node.SameLevelAs) == 0 {
+			continue
+		}
+		first := findRoot(node.Name)
+		second := findRoot(node.SameLevelAs)
+		// Nodes are visited in declaration order. Keep the node declared first as the root.
+		if first == second {
+			continue
+		}
+		for _, other := range nodes {
+			if other.Name == first {
+				parent[second] = first
+				break
+			}
+			if other.Name == second {
+				parent[first] = second
+				break
+			}
+		}
+	}
+
+	group := make(map[string]string, len(nodes))
+	for _, node := range nodes {
+		group[node.Name] = findRoot(node.Name)
+	}
+	return group
+}
+
+// Compute the levels allowed for every node by the level hints and the dependencies.
+// A node must be at least 1 level above all its dependencies. Contradictions are returned as
+// diagnostics (positions are not filled). Dependencies must be valid and without cycles.
+func computeLevelHintBounds(nodes []NodeInputFields) (*levelHintBounds, DiagnosticList) {
+	var diags DiagnosticList
+	bounds := &levelHintBounds{
+		group:    findSameLevelGroups(nodes),
+		minLevel: map[string]int{},
+		maxLevel: map[string]int{},
+	}
+
+	declOrder := make(map[string]int, len(nodes))
+	for idx, node := range nodes {
+		declOrder[node.Name] = idx
+	}
+	// Node giving the level for every group (if any)
+	fixedBy := map[string]string{}
+	for _, node := range nodes {
+		group := bounds.group[node.Name]
+		bounds.minLevel[group] = 0
+		bounds.maxLevel[group] = unboundedLevel
+		if node.Level == nil {
+			continue
+		}
+		if firstName, found := fixedBy[group]; found {
+			firstLevel := *nodes[declOrder[firstName]].Level
+			if firstLevel != *node.Level {
+				diags.addError(SourcePos{}, node.Name,
+					"level %v of node '%v' contradicts level %v of node '%v' (same-level-as)",
+					*node.Level, node.Name, firstLevel, firstName)
+			}
+			continue
+		}
+		fixedBy[group] = node.Name
+	}
+	for _, node := range nodes {
+		group := bounds.group[node.Name]
+		if node.Level != nil && fixedBy[group] == node.Name {
+			bounds.minLevel[group] = *node.Level
+			bounds.maxLevel[group] = *node.Level
+		}
+	}
+
+	// A node can not be in the same level as its dependency
+	for _, node := range nodes {
+		for _, dep := range node.DependsOn {
+			if bounds.group[dep.Name] == bounds.group[node.Name] {
+				diags.addError(SourcePos{}, node.Name,
+					"node '%v' can not be in the same level as its dependency '%v' (same-level-as)",
+					node.Name, dep.Name)
+			}
+		}
+	}
+	if len(diags) > 0 {
+		return bounds, diags
+	}
+
+	// Bound of a group as seen by the linked groups. For groups with a given level, the level
+	// is used, so that a contradiction is reported only for the nodes involved.
+	getMinLevel := func(group string) int {
+		if fixerName, fixed := fixedBy[group]; fixed {
+			return *nodes[declOrder[fixerName]].Level
+		}
+		return bounds.minLevel[group]
+	}
+	getMaxLevel := func(group string) int {
+		if fixerName, fixed := fixedBy[group]; fixed {
+			return *nodes[declOrder[fixerName]].Level
+		}
+		return bounds.maxLevel[group]
+	}
+
+	// Raise the min levels based on the dependencies, and lower the max levels based on the
+	// users. Groups can make this go around in circles, so the number of rounds is limited.
+	// The dependency which gave the min level of every group is kept for the error messages.
+	minLevelCause := map[string]levelBoundCause{}
+	for round := 0; ; round++ {
+		changed := false
+		for _, node := range nodes {
+			group := bounds.group[node.Name]
+			for _, dep := range node.DependsOn {
+				depGroup := bounds.group[dep.Name]
+				if getMinLevel(depGroup)+1 > bounds.minLevel[group] {
+					bounds.minLevel[group] = getMinLevel(depGroup) + 1
+					minLevelCause[group] = levelBoundCause{node.Name, dep.Name}
+					changed = true
+				}
+				maxLevel := getMaxLevel(group)
+				if maxLevel != unboundedLevel && maxLevel-1 < bounds.maxLevel[depGroup] {
+					bounds.maxLevel[depGroup] = maxLevel - 1
+					changed = true
+				}
+			}
+		}
+		if !changed {
+			break
+		}
+		if round > len(nodes) {
+			diags.addError(SourcePos{}, nodes[0].Name,
+				"same-level-as hints can not be satisfied with the dependencies")
+			return bounds, diags
+		}
+	}
+
+	// Free nodes can always be moved up. So, every contradiction shows up as a node with a
+	// level lower than what its dependencies need.
+	for _, node := range nodes {
+		group := bounds.group[node.Name]
+		if node.Level != nil && fixedBy[group] == node.Name && bounds.minLevel[group] > *node.Level {
+			reasons := explainMinLevel(node.Name, bounds, fixedBy, minLevelCause, nodes, declOrder)
+			diags.addError(SourcePos{}, node.Name,
+				"level %v of node '%v' is too low: it needs level %v or above, since %s",
+				*node.Level, node.Name, bounds.minLevel[group], strings.Join(reasons, ", "))
+		}
+	}
+	return bounds, diags
+}
+
+// The dependency which raised the min level of a same-level-as group
+type levelBoundCause struct {
+	// Node in the group
+	node string
+	// Dependency of the node
+	dep string
+}
+
+// Return the chain of dependencies and same-level-as hints giving the min level of the group
+// of the node (eg: ["'d' is same-level-as 'c'", "'c' depends on 'b'", "'b' has level 1"]).
+func explainMinLevel(name string, bounds *levelHintBounds, fixedBy map[string]string,
+	minLevelCause map[string]levelBoundCause, nodes []NodeInputFields,
+	declOrder map[string]int) []string {
+	reasons := make([]string, 0, defaultCapacity)
+	current := name
+	// The chain can not be longer than the number of groups
+	for step := 0; step <= len(nodes); step++ {
+		group := bounds.group[current]
+		if fixerName, fixed := fixedBy[group]; fixed && step > 0 {
+			if fixerName != current {
+				pushBack(&reasons, fmt.Sprintf("'%v' is same-level-as '%v'", current, fixerName))
+			}
+			pushBack(&reasons, fmt.Sprintf("'%v' has level %v", fixerName,
+				*nodes[declOrder[fixerName]].Level))
+			break
+		}
+		cause, found := minLevelCause[group]
+		if !found {
+			break
+		}
+		if cause.node != current {
+			pushBack(&reasons, fmt.Sprintf("'%v' is same-level-as '%v'", current, cause.node))
+		}
+		pushBack(&reasons, fmt.Sprintf("'%v' depends on '%v'", cause.node, cause.dep))
+		current = cause.dep
+	}
+	return reasons
+}
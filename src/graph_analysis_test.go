@@ -0,0 +1,187 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Create nodes from specs like "a: b c" (node a depends on b and c)
+func makeTestNodes(specs ...string) []NodeInputFields {
+	nodes := make([]NodeInputFields, 0, len(specs))
+	for _, spec := range specs {
+		name, deps, _ := strings.Cut(spec, ":")
+		node := NodeInputFields{Name: strings.TrimSpace(name)}
+		for _, dep := range strings.Fields(deps) {
+			pushBack(&node.DependsOn, DependencyFields{Name: dep})
+		}
+		pushBack(&nodes, node)
+	}
+	return nodes
+}
+
+func TestFindDependencyCycles(t *testing.T) {
+	tests := []struct {
+		name  string
+		nodes []NodeInputFields
+		// Expected "node: message" for every diagnostic
+		want []string
+	}{
+		{
+			name:  "no cycles",
+			nodes: makeTestNodes("a:", "b: a", "c: a b"),
+			want:  []string{},
+		},
+		{
+			name:  "3-cycle and self-loop",
+			nodes: makeTestNodes("a: b", "b: c", "c: a", "d: d", "e: a"),
+			want: []string{
+				"a: dependency cycle: a -> b -> c -> a",
+				"d: dependency cycle: d -> d",
+			},
+		},
+		{
+			name:  "cycle group larger than the cycle shown",
+			nodes: makeTestNodes("a: b", "b: a c", "c: b"),
+			want: []string{
+				"a: dependency cycle: a -> b -> a (all 3 nodes in this cycle group: a, b, c)",
+			},
+		},
+		{
+			name:  "cycle reported from the node declared first",
+			nodes: makeTestNodes("x:", "c: a", "b: c", "a: b x"),
+			want:  []string{"c: dependency cycle: c -> a -> b -> c"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := []string{}
+			for _, diag := range findDependencyCycles(test.nodes) {
+				if diag.Severity != severityError {
+					t.Errorf("expected an error, got %s: %s", diag.Severity, diag.Message)
+				}
+				pushBack(&got, diag.Node+": "+diag.Message)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestFindStronglyConnectedComponents(t *testing.T) {
+	nodes := makeTestNodes("a: b", "b: c", "c: a", "d: d", "e: a")
+	components := findStronglyConnectedComponents(nodes)
+
+	sizes := map[string]int{}
+	for _, component := range components {
+		for _, name := range component {
+			sizes[name] = len(component)
+		}
+	}
+	want := map[string]int{"a": 3, "b": 3, "c": 3, "d": 1, "e": 1}
+	if !reflect.DeepEqual(sizes, want) {
+		t.Errorf("component sizes: got %v, want %v", sizes, want)
+	}
+	// Dependencies come before the nodes depending on them (reverse topological order)
+	if len(components) != 3 || components[2][0] != "e" {
+		t.Errorf("unexpected component order: %v", components)
+	}
+}
+
+func TestComputeLevelHintBoundsReportsCause(t *testing.T) {
+	level := func(value int) *int { return &value }
+	tests := []struct {
+		name  string
+		nodes []NodeInputFields
+		setup func(nodes []NodeInputFields)
+		want  string
+	}{
+		{
+			name:  "direct dependency",
+			nodes: makeTestNodes("a:", "b: a", "c: b"),
+			setup: func(nodes []NodeInputFields) { nodes[1].Level = level(0) },
+			want: "b: level 0 of node 'b' is too low: it needs level 1 or above, " +
+				"since 'b' depends on 'a'",
+		},
+		{
+			name:  "through same-level-as",
+			nodes: makeTestNodes("a:", "b: a", "c: b", "d:"),
+			setup: func(nodes []NodeInputFields) {
+				nodes[2].SameLevelAs = "d"
+				nodes[3].Level = level(1)
+			},
+			want: "d: level 1 of node 'd' is too low: it needs level 2 or above, " +
+				"since 'd' is same-level-as 'c', 'c' depends on 'b', 'b' depends on 'a'",
+		},
+		{
+			name:  "chain ending at a node with a level",
+			nodes: makeTestNodes("a:", "e:", "b: e", "c: b"),
+			setup: func(nodes []NodeInputFields) {
+				nodes[0].Level = level(3)
+				nodes[1].SameLevelAs = "a"
+				nodes[3].Level = level(4)
+			},
+			want: "c: level 4 of node 'c' is too low: it needs level 5 or above, since " +
+				"'c' depends on 'b', 'b' depends on 'e', 'e' is same-level-as 'a', 'a' has level 3",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.setup(test.nodes)
+			_, diags := computeLevelHintBounds(test.nodes)
+			if len(diags) != 1 {
+				t.Fatalf("expected 1 diagnostic, got %v", diags)
+			}
+			if got := diags[0].Node + ": " + diags[0].Message; got != test.want {
+				t.Errorf("got %q\nwant %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestFindRedundantDependencies(t *testing.T) {
+	tests := []struct {
+		name  string
+		nodes []NodeInputFields
+		// Expected "node.dependency via other" for every redundant dependency
+		want []string
+	}{
+		{
+			name:  "no redundant dependencies",
+			nodes: makeTestNodes("a:", "b: a", "c: a", "d: b c"),
+			want:  []string{},
+		},
+		{
+			name:  "triangle",
+			nodes: makeTestNodes("a:", "b: a", "c: b a"),
+			want:  []string{"c.a via b"},
+		},
+		{
+			name:  "long chain",
+			nodes: makeTestNodes("a:", "b: a", "c: b", "d: a c b"),
+			want:  []string{"d.a via c", "d.b via c"},
+		},
+		{
+			name:  "several nodes",
+			nodes: makeTestNodes("a:", "b: a", "c: a b", "d: a c"),
+			want:  []string{"c.a via b", "d.a via c"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := []string{}
+			for _, item := range findRedundantDependencies(test.nodes) {
+				node := test.nodes[item.nodeIdx]
+				pushBack(&got, node.Name+"."+node.DependsOn[item.depIdx].Name+" via "+
+					item.through)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,267 @@
+// This file handles the export subcommand, which writes the graph in formats other than HTML.
+// The graph goes through the same loading and layout steps as for the HTML output, so the
+// exported graph has the same nodes, links and levels.
+//
+// dot: Graphviz DOT. Nodes of a level get the same rank. Importance is shown with the pen
+// width, font size and style of the node. Resource links become URL attributes.
+// mermaid: Mermaid flowchart, to be embedded in Markdown. Resource links become click
+// directives.
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+)
+
+type ExportArgs struct {
+	Graph   string `arg:"positional" default:"graph.yaml" help:"graph file or directory"`
+	Format  string `arg:"-f,--format,required" help:"output format: dot, mermaid"`
+	OutFile string `arg:"-o,--out" help:"output file [default: stdout]"`
+}
+
+// DOT attributes of the nodes for every importance
+var dotImportanceAttrs = map[string]string{
+	"lowest":  `style="rounded,dashed", fontsize=10, color=gray60, fontcolor=gray40`,
+	"lower":   `style="rounded,dashed", fontsize=12`,
+	"low":     `fontsize=13`,
+	"normal":  ``,
+	"high":    `penwidth=2, fontsize=16`,
+	"higher":  `penwidth=3, fontsize=18`,
+	"highest": `style="rounded,bold,filled", penwidth=3, fontsize=20, fillcolor=lightyellow`,
+}
+
+// Default line style for the well known dependency types (same as main.js)
+var edgeTypeDefaultStyles = map[string]string{
+	"motivates":  "dashed",
+	"example-of": "dotted",
+}
+
+// Return the ids of the nodes the given node depends on. The ids are swapped for
+// node-sorting: descend (see handleNodeSorting).
+func getDependencyIdsForExport(algoConfig *AlgoConfigFields, node *NodeData) []int {
+	if algoConfig.NodeSorting == "descend" {
+		return node.IntIdFields.UsedByIds
+	}
+	return node.IntIdFields.DependsOnIds
+}
+
+// Return the direction of the exported graph (TB, BT, LR or RL), so that it looks like the
+// HTML output: dependencies below the nodes (above for node-sorting: descend), or on the left
+// (right) with orientation: horizontal. Links go from the node to its dependency with
+// arrow-direction: child2parent, and from the dependency to the node otherwise.
+func getExportDirection(algoConfig *AlgoConfigFields) string {
+	// Direction of the arrows for child2parent and the reverse of it
+	forward, reverse := "TB", "BT"
+	if algoConfig.Orientation == "horizontal" {
+		forward, reverse = "RL", "LR"
+	}
+	if algoConfig.ArrowDirection == "parent2child" {
+		forward, reverse = reverse, forward
+	}
+	if algoConfig.NodeSorting == "descend" {
+		forward = reverse
+	}
+	return forward
+}
+
+// Mermaid styles for the importance of nodes (normal has no style)
+var mermaidImportanceStyles = map[string]string{
+	"lowest":  "stroke-dasharray:4 4,font-size:10px,color:#999",
+	"lower":   "stroke-dasharray:4 4,font-size:12px",
+	"low":     "font-size:13px",
+	"high":    "stroke-width:2px,font-size:16px",
+	"higher":  "stroke-width:3px,font-size:18px",
+	"highest": "stroke-width:3px,font-size:20px,fill:#ffffe0",
+}
+
+// Escape the text for a quoted string in DOT
+func escapeDotString(text string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", "")
+	return replacer.Replace(text)
+}
+
+// Return the graph in Graphviz DOT format
+func exportGraphAsDot(gdfData *GdfDataStruct, nodes []NodeData) string {
+	algoConfig := &gdfData.AlgoConfig
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "digraph \"%s\" {\n", escapeDotString(gdfData.HeadConfig.Title))
+	fmt.Fprintf(&builder, "    rankdir=%s;\n", getExportDirection(algoConfig))
+	fmt.Fprintf(&builder, "    node [shape=box, style=rounded];\n")
+
+	levelMap := map[int][]string{}
+	for idx := range nodes {
+		node := &nodes[idx]
+		label := node.InputFields.Title
+		if len(node.InputFields.Subtitle) > 0 {
+			label += "\n" + node.InputFields.Subtitle
+		}
+		attrs := []string{fmt.Sprintf(`label="%s"`, escapeDotString(label))}
+		importanceAttrs := dotImportanceAttrs[node.InputFields.Importance]
+		if len(importanceAttrs) > 0 {
+			pushBack(&attrs, importanceAttrs)
+		}
+		if len(node.ElemFields.Link) > 0 {
+			pushBack(&attrs, fmt.Sprintf(`URL="%s"`, escapeDotString(node.ElemFields.Link)))
+		}
+		fmt.Fprintf(&builder, "    \"%s\" [%s];\n", node.InputFields.Name,
+			strings.Join(attrs, ", "))
+		level := node.Position.Level
+		levelMap[level] = append(levelMap[level], node.InputFields.Name)
+	}
+
+	for idx := range nodes {
+		node := &nodes[idx]
+		for _, depId := range getDependencyIdsForExport(algoConfig, node) {
+			source, target := node.InputFields.Name, nodes[depId].InputFields.Name
+			if algoConfig.ArrowDirection == "parent2child" {
+				source, target = target, source
+			}
+			link := getDependencyFieldsForLink(nodes, idx, depId)
+			style := link.Style
+			if len(style) == 0 {
+				style = edgeTypeDefaultStyles[link.Type]
+			}
+			attrs := make([]string, 0, 2)
+			if len(link.Label) > 0 {
+				pushBack(&attrs, fmt.Sprintf(`label="%s"`, escapeDotString(link.Label)))
+			}
+			if len(style) > 0 && style != "solid" {
+				pushBack(&attrs, fmt.Sprintf("style=%s", style))
+			}
+			fmt.Fprintf(&builder, "    \"%s\" -> \"%s\"", source, target)
+			if len(attrs) > 0 {
+				fmt.Fprintf(&builder, " [%s]", strings.Join(attrs, ", "))
+			}
+			fmt.Fprintf(&builder, ";\n")
+		}
+	}
+
+	// Keep the levels from the layout
+	levels := make([]int, 0, len(levelMap))
+	for level := range levelMap {
+		pushBack(&levels, level)
+	}
+	sort.Ints(levels)
+	for _, level := range levels {
+		fmt.Fprintf(&builder, "    { rank=same; \"%s\"; }\n",
+			strings.Join(levelMap[level], "\"; \""))
+	}
+	fmt.Fprintf(&builder, "}\n")
+	return builder.String()
+}
+
+// Escape the text for a label in Mermaid. Special characters are replaced with entity codes.
+func escapeMermaidText(text string) string {
+	replacer := strings.NewReplacer("#", "#35;", `"`, "#quot;", "<", "#lt;", ">", "#gt;",
+		"&", "#amp;", "|", "#124;", "\n", " ", "\r", "")
+	return replacer.Replace(text)
+}
+
+// Id of the node in Mermaid. Node names are prefixed, since some names (eg: end) are keywords
+// in Mermaid.
+func getMermaidNodeId(node *NodeData) string {
+	return "n_" + node.InputFields.Name
+}
+
+// Return the graph as a Mermaid flowchart
+func exportGraphAsMermaid(gdfData *GdfDataStruct, nodes []NodeData) string {
+	algoConfig := &gdfData.AlgoConfig
+	var builder strings.Builder
+	if len(gdfData.HeadConfig.Title) > 0 {
+		// Front matter is YAML. Its quoted strings are escaped like the ones in DOT.
+		fmt.Fprintf(&builder, "---\ntitle: \"%s\"\n---\n",
+			escapeDotString(gdfData.HeadConfig.Title))
+	}
+	fmt.Fprintf(&builder, "flowchart %s\n", getExportDirection(algoConfig))
+
+	for idx := range nodes {
+		node := &nodes[idx]
+		label := escapeMermaidText(node.InputFields.Title)
+		if len(node.InputFields.Subtitle) > 0 {
+			label += "<br/><small>" + escapeMermaidText(node.InputFields.Subtitle) + "</small>"
+		}
+		fmt.Fprintf(&builder, "    %s[\"%s\"]\n", getMermaidNodeId(node), label)
+	}
+
+	for idx := range nodes {
+		node := &nodes[idx]
+		for _, depId := range getDependencyIdsForExport(algoConfig, node) {
+			source, target := getMermaidNodeId(node), getMermaidNodeId(&nodes[depId])
+			if algoConfig.ArrowDirection == "parent2child" {
+				source, target = target, source
+			}
+			link := getDependencyFieldsForLink(nodes, idx, depId)
+			style := link.Style
+			if len(style) == 0 {
+				style = edgeTypeDefaultStyles[link.Type]
+			}
+			// Mermaid has only one style of broken lines
+			arrow := "-->"
+			if style == "dashed" || style == "dotted" {
+				arrow = "-.->"
+			}
+			if len(link.Label) > 0 {
+				arrow += "|\"" + escapeMermaidText(link.Label) + "\"|"
+			}
+			fmt.Fprintf(&builder, "    %s %s %s\n", source, arrow, target)
+		}
+	}
+
+	for idx := range nodes {
+		node := &nodes[idx]
+		style := mermaidImportanceStyles[node.InputFields.Importance]
+		if len(style) > 0 {
+			fmt.Fprintf(&builder, "    style %s %s\n", getMermaidNodeId(node), style)
+		}
+	}
+
+	// Resources open in a new tab, like the middle-click in the HTML page
+	for idx := range nodes {
+		node := &nodes[idx]
+		if len(node.ElemFields.Link) > 0 {
+			link := strings.ReplaceAll(node.ElemFields.Link, `"`, "%22")
+			fmt.Fprintf(&builder, "    click %s href \"%s\" _blank\n", getMermaidNodeId(node),
+				link)
+		}
+	}
+	return builder.String()
+}
+
+// Run the export subcommand: write the graph in the requested format.
+func runExportCommand(args *ExportArgs) error {
+	exporters := map[string]func(*GdfDataStruct, []NodeData) string{
+		"dot":     exportGraphAsDot,
+		"mermaid": exportGraphAsMermaid,
+	}
+	exporter, ok := exporters[args.Format]
+	if !ok {
+		return fmt.Errorf("unsupported export format: '%s' (supported: dot, mermaid)",
+			args.Format)
+	}
+
+	graphFile := getGraphFilePath(args.Graph)
+	gdfData, readable, err := loadGdf(graphFile)
+	if !readable {
+		return fmt.Errorf("graph file %s not readable: %s", graphFile, err)
+	}
+	if err != nil {
+		return err
+	}
+	for _, warning := range gdfData.warnings {
+		log.Printf("Warning: %s", warning)
+	}
+	nodes, err := createComputeAndFillNodeDataList(gdfData)
+	if err != nil {
+		return err
+	}
+
+	output := exporter(gdfData, nodes)
+	if len(args.OutFile) == 0 {
+		_, err = fmt.Print(output)
+		return err
+	}
+	return os.WriteFile(args.OutFile, []byte(output), 0644)
+}
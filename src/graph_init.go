@@ -0,0 +1,118 @@
+// This file handles the init subcommand, which creates a directory for a new graph.
+// The directory gets a starter graph.yaml (from one of the templates in linkitall_templates),
+// a resources directory, and the asset and vendor files. Existing files are not replaced
+// unless --force is given.
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const templatesDirName = "linkitall_templates"
+
+type InitArgs struct {
+	Dir      string `arg:"positional,required" help:"directory for the new graph"`
+	Template string `arg:"-t,--template" default:"minimal" help:"minimal, course or glossary"`
+	Force    bool   `arg:"-f,--force" help:"replace existing files"`
+}
+
+// Return the names of the templates available for init
+func getInitTemplateNames() []string {
+	entries, err := fs.ReadDir(embeddedFiles, templatesDirName)
+	names := make([]string, 0, len(entries))
+	if err != nil {
+		return names
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			pushBack(&names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Return the files of the template (paths relative to the template directory)
+func getInitTemplateFiles(templateName string) ([]string, error) {
+	templateDir := path.Join(templatesDirName, templateName)
+	files := make([]string, 0, defaultCapacity)
+	err := fs.WalkDir(embeddedFiles, templateDir, func(srcPath string, entry fs.DirEntry,
+		err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() {
+			pushBack(&files, strings.TrimPrefix(srcPath, templateDir+"/"))
+		}
+		return nil
+	})
+	return files, err
+}
+
+// Run the init subcommand: create the directory with the files of the template.
+// Nothing is written if any of the files already exist (unless forced).
+func runInitCommand(args *InitArgs) error {
+	templateNames := getInitTemplateNames()
+	found := false
+	for _, name := range templateNames {
+		found = found || name == args.Template
+	}
+	if !found {
+		return fmt.Errorf("unknown template '%s' (available: %s)", args.Template,
+			strings.Join(templateNames, ", "))
+	}
+	files, err := getInitTemplateFiles(args.Template)
+	if err != nil {
+		return err
+	}
+
+	if !args.Force {
+		existing := make([]string, 0)
+		for _, file := range files {
+			targetPath := filepath.Join(args.Dir, filepath.FromSlash(file))
+			if isPathAccessible(targetPath, "file") {
+				pushBack(&existing, targetPath)
+			}
+		}
+		if len(existing) > 0 {
+			return fmt.Errorf("files already exist (use --force to replace them): %s",
+				strings.Join(existing, ", "))
+		}
+	}
+
+	// The resources directory is created even if the template has no resources
+	err = os.MkdirAll(filepath.Join(args.Dir, "resources"), 0755)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		data, err := embeddedFiles.ReadFile(path.Join(templatesDirName, args.Template, file))
+		if err != nil {
+			return err
+		}
+		targetPath := filepath.Join(args.Dir, filepath.FromSlash(file))
+		err = os.MkdirAll(filepath.Dir(targetPath), 0755)
+		if err != nil {
+			return err
+		}
+		err = os.WriteFile(targetPath, data, 0644)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Created %s\n", targetPath)
+	}
+
+	// Assets are needed for opening the page generated from the graph
+	err = copyAssetsAndVendorFilesToDir(args.Dir, args.Force, false)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Build the graph with: linkitall build %s\n", args.Dir)
+	return nil
+}
@@ -0,0 +1,122 @@
+// This file handles the validate subcommand. The graph is loaded and the layout is computed
+// as for a build, but nothing is written. The problems found are printed as text, or as JSON
+// for CI and editor integrations. The exit status is non-zero if there are errors.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+type ValidateArgs struct {
+	Graph string `arg:"positional" default:"graph.yaml" help:"graph file or directory"`
+	Json  bool   `arg:"--json" help:"print the diagnostics as JSON"`
+}
+
+// A diagnostic in the JSON output of the validate subcommand
+type jsonDiagnostic struct {
+	Severity string `json:"severity"`
+	Node     string `json:"node"`
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+	Message  string `json:"message"`
+}
+
+// JSON output of the validate subcommand
+type jsonValidationResult struct {
+	Valid       bool             `json:"valid"`
+	NumErrors   int              `json:"errors"`
+	NumWarnings int              `json:"warnings"`
+	Diagnostics []jsonDiagnostic `json:"diagnostics"`
+}
+
+// Load the graph and compute the layout, without writing anything.
+// Returns all the problems found (errors and warnings).
+func validateGraph(graphFile string) DiagnosticList {
+	var diags DiagnosticList
+	filePos := SourcePos{File: graphFile}
+	gdfData, readable, err := loadGdf(graphFile)
+	if !readable {
+		diags.addError(filePos, "", "graph file not readable: %s", err)
+		return diags
+	}
+	if err != nil {
+		diags.addFromError(filePos, err)
+		return diags
+	}
+	diags = append(diags, gdfData.warnings...)
+
+	_, err = createComputeAndFillNodeDataList(gdfData)
+	var layoutErr *LayoutError
+	if errors.As(err, &layoutErr) {
+		// Report the layout error at the node involved
+		for idx := range gdfData.Nodes {
+			if gdfData.Nodes[idx].Name == layoutErr.Node {
+				filePos = gdfData.Nodes[idx].source.find("name")
+			}
+		}
+	}
+	diags.addFromError(filePos, err)
+	return diags
+}
+
+// Print the diagnostics as JSON (see jsonValidationResult)
+func printDiagnosticsAsJson(diags DiagnosticList) error {
+	result := jsonValidationResult{Diagnostics: make([]jsonDiagnostic, 0, len(diags))}
+	for _, diag := range diags {
+		if diag.Severity == severityError {
+			result.NumErrors++
+		} else {
+			result.NumWarnings++
+		}
+		pushBack(&result.Diagnostics, jsonDiagnostic{diag.Severity, diag.Node, diag.Pos.File,
+			diag.Pos.Line, diag.Pos.Col, diag.Message})
+	}
+	result.Valid = result.NumErrors == 0
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	// The output is not for HTML. Keep "->" and such as they are.
+	encoder.SetEscapeHTML(false)
+	return encoder.Encode(result)
+}
+
+// Print the diagnostics as text (one per line) followed by a summary
+func printDiagnosticsAsText(graphFile string, diags DiagnosticList) {
+	numErrors := 0
+	for _, diag := range diags {
+		if diag.Severity == severityError {
+			numErrors++
+		}
+		if len(diag.Pos.File) == 0 {
+			fmt.Printf("%s: %s\n", diag.Severity, diag.Message)
+		} else {
+			fmt.Printf("%s: %s: %s\n", diag.Pos, diag.Severity, diag.Message)
+		}
+	}
+	fmt.Printf("%s: %d errors, %d warnings\n", graphFile, numErrors, len(diags)-numErrors)
+}
+
+// Run the validate subcommand. Returns the exit status: 1 if there are errors, 0 otherwise.
+func runValidateCommand(args *ValidateArgs) int {
+	graphFile := getGraphFilePath(args.Graph)
+
+	diags := validateGraph(graphFile)
+	if args.Json {
+		err := printDiagnosticsAsJson(diags)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "unable to write diagnostics: %s\n", err)
+			return 1
+		}
+	} else {
+		printDiagnosticsAsText(graphFile, diags)
+	}
+
+	if diags.asError() != nil {
+		return 1
+	}
+	return 0
+}
@@ -3,18 +3,32 @@ package main
 
 import (
 	"html/template"
+	"log"
 	"os"
+	"path"
+	"path/filepath"
 )
 
 // Info about the board (outer board used for holding all the nodes)
 type BoardConfigFields struct {
 	Width  int
 	Height int
+	// Regions shown behind the groups of nodes
+	GroupRegions []GroupRegionFields
 }
 
 type ControlConfigFields struct {
 	// In release mode, we use CDN for all the vendor files
 	Release bool
+	// In single-file mode, CSS and JS files are inlined in the HTML
+	SingleFile bool
+}
+
+// Contents of the asset and vendor files inlined in single-file mode
+type InlineAssetFields struct {
+	StyleCss     template.CSS
+	MainJs       template.JS
+	LeaderLineJs template.JS
 }
 
 // All the data required for generating HTML page from template is stored in this struct
@@ -27,26 +41,45 @@ type TemplateData struct {
 	BoardConfig BoardConfigFields
 	// Controlling template generation
 	ControlConfig ControlConfigFields
+	// Only filled in single-file mode
+	InlineAssets InlineAssetFields
 }
 
 func computeBoardConfig(gdfData *GdfDataStruct, nodes []NodeData) BoardConfigFields {
 	extraWidth := 10
 	nodeBoxWidthPx := gdfData.DisplayConfig.NodeBoxWidthPx
-	// Not configurable for now (but it shouldn't matter much anyway)
-	nodeBoxHeightPx := 150
-	maxWidth := 0
-	maxHeight := 0
-	// Initially compute max of left and top. Then add node width and height.
-	for _, node := range nodes {
-		if node.ElemFields.LeftPx > maxWidth {
-			maxWidth = node.ElemFields.LeftPx
+	maxRight := 0
+	maxBottom := 0
+	updateMax := func(right int, bottom int) {
+		if right > maxRight {
+			maxRight = right
 		}
-		if node.ElemFields.TopPx > maxHeight {
-			maxHeight = node.ElemFields.TopPx
+		if bottom > maxBottom {
+			maxBottom = bottom
 		}
 	}
+	for _, node := range nodes {
+		updateMax(node.ElemFields.LeftPx+nodeBoxWidthPx,
+			node.ElemFields.TopPx+node.ElemFields.HeightPx)
+		// Waypoints can take the last slot of a level. Get the slot edges from the route
+		// points (which are on the edges of the slot).
+		for _, dot := range node.ElemFields.DependsOnDots {
+			for _, point := range dot.Waypoints {
+				if gdfData.AlgoConfig.Orientation == "horizontal" {
+					updateMax(point.X, point.Y+gdfData.DisplayConfig.NodeBoxHeightPx/2)
+				} else {
+					updateMax(point.X+nodeBoxWidthPx/2, point.Y)
+				}
+			}
+		}
+	}
+
+	groupRegions := computeGroupRegions(gdfData, nodes)
+	for _, region := range groupRegions {
+		updateMax(region.LeftPx+region.WidthPx, region.TopPx+region.HeightPx)
+	}
 
-	return BoardConfigFields{maxWidth + nodeBoxWidthPx + extraWidth, maxHeight + nodeBoxHeightPx}
+	return BoardConfigFields{maxRight + extraWidth, maxBottom, groupRegions}
 }
 
 // Constructor for TemplateData. There are some fields like BoardConfig that needs to be
@@ -54,16 +87,70 @@ func computeBoardConfig(gdfData *GdfDataStruct, nodes []NodeData) BoardConfigFie
 func newTemplateData(gdfData *GdfDataStruct,
 	nodes []NodeData, controlConfig ControlConfigFields) TemplateData {
 	boardConfig := computeBoardConfig(gdfData, nodes)
-	return TemplateData{gdfData, nodes, boardConfig, controlConfig}
+	return TemplateData{gdfData, nodes, boardConfig, controlConfig, InlineAssetFields{}}
+}
+
+// Parse the template file. If the file is not available, use the template embedded in the
+// executable instead.
+func loadTemplate(templateFile string) (*template.Template, error) {
+	if isPathAccessible(templateFile, "file") {
+		return template.ParseFiles(templateFile)
+	}
+
+	log.Printf("Template %s not found. Using embedded template\n", templateFile)
+	return template.ParseFS(embeddedFiles, path.Join(assetDirName, "template.html"))
+}
+
+// Read an asset file from the asset dir. If the file is not available, use the embedded file.
+func readAssetFile(assetDir string, name string) ([]byte, error) {
+	assetFile := filepath.Join(assetDir, name)
+	if isPathAccessible(assetFile, "file") {
+		return os.ReadFile(assetFile)
+	}
+	return readEmbeddedAssetFile(name)
+}
+
+// Load the contents of CSS and JS files to be inlined in the HTML.
+// Asset files come from the asset dir (if available). Vendor files always come from the
+// embedded files.
+func loadInlineAssets(assetDir string) (InlineAssetFields, error) {
+	var inlineAssets InlineAssetFields
+
+	styleCss, err := readAssetFile(assetDir, "style.css")
+	if err != nil {
+		return inlineAssets, err
+	}
+	mainJs, err := readAssetFile(assetDir, "main.js")
+	if err != nil {
+		return inlineAssets, err
+	}
+	leaderLineJs, err := readEmbeddedVendorFile(leaderLineVendorPath)
+	if err != nil {
+		return inlineAssets, err
+	}
+
+	inlineAssets.StyleCss = template.CSS(styleCss)
+	inlineAssets.MainJs = template.JS(mainJs)
+	inlineAssets.LeaderLineJs = template.JS(leaderLineJs)
+	return inlineAssets, nil
 }
 
 // The function responsible for generating the final HTML from template
+// In single-file mode, the CSS and JS files are inlined. They are taken from the same dir
+// as the template file.
 func fillTemplateWriteOutput(templateFile string, data TemplateData, outputFile string) error {
-	tmpl, err := template.ParseFiles(templateFile)
+	tmpl, err := loadTemplate(templateFile)
 	if err != nil {
 		return err
 	}
 
+	if data.ControlConfig.SingleFile {
+		data.InlineAssets, err = loadInlineAssets(filepath.Dir(templateFile))
+		if err != nil {
+			return err
+		}
+	}
+
 	writer, err := os.Create(outputFile)
 	if err != nil {
 		return err
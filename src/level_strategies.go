@@ -0,0 +1,113 @@
+// This file contains level strategies other than the basic ones in node_computation.go.
+//
+// coffman-graham: Levels are assigned with the Coffman-Graham algorithm. A level gets at most
+// max-nodes-per-level nodes (algo-config), so the graph does not become very wide when there
+// are many nodes without dependencies. This can increase the number of levels.
+package main
+
+import (
+	"sort"
+)
+
+// Compare the label lists (sorted in decreasing order) used by the Coffman-Graham algorithm.
+// Returns true if first comes before second lexicographically.
+func isLabelListBefore(first []int, second []int) bool {
+	for idx := 0; idx < len(first) && idx < len(second); idx++ {
+		if first[idx] != second[idx] {
+			return first[idx] < second[idx]
+		}
+	}
+	return len(first) < len(second)
+}
+
+// Coffman-Graham labeling. Nodes are labeled 1..n starting from the nodes without users. Among
+// the nodes with all users labeled, the one with the smallest (decreasing sorted) list of user
+// labels is labeled next. Ties are broken using the declaration order.
+func computeCoffmanGrahamLabels(nodes []NodeData) ([]int, error) {
+	labels := make([]int, len(nodes))
+	for nextLabel := 1; nextLabel <= len(nodes); nextLabel++ {
+		bestId := -1
+		var bestUserLabels []int
+		for idx := range nodes {
+			if labels[idx] != 0 {
+				continue
+			}
+			userLabels := make([]int, 0, len(nodes[idx].IntIdFields.UsedByIds))
+			allLabeled := true
+			for _, userId := range nodes[idx].IntIdFields.UsedByIds {
+				if labels[userId] == 0 {
+					allLabeled = false
+					break
+				}
+				pushBack(&userLabels, labels[userId])
+			}
+			if !allLabeled {
+				continue
+			}
+			sort.Sort(sort.Reverse(sort.IntSlice(userLabels)))
+			if bestId < 0 || isLabelListBefore(userLabels, bestUserLabels) {
+				bestId = idx
+				bestUserLabels = userLabels
+			}
+		}
+		if bestId < 0 {
+			return labels, newLayoutError("", layoutCheckReachability,
+				"unable to label nodes for coffman-graham (dependency cycle?)")
+		}
+		labels[bestId] = nextLabel
+	}
+	return labels, nil
+}
+
+// Compute levels with the Coffman-Graham algorithm. Levels are filled from level 0. A node can
+// be placed in the current level once all its dependencies are in the levels below. Among such
+// nodes, the one with the highest label is placed first. maxNodesPerLevel <= 0 means no limit.
+func computeCoffmanGrahamLevels(maxNodesPerLevel int, nodes []NodeData) error {
+	labels, err := computeCoffmanGrahamLabels(nodes)
+	if err != nil {
+		return err
+	}
+
+	for idx := range nodes {
+		nodes[idx].Position.Level = defaultInvalidLevel
+	}
+
+	currentLevel := 0
+	numNodesInLevel := 0
+	for numPlaced := 0; numPlaced < len(nodes); {
+		bestId := -1
+		for idx := range nodes {
+			node := &nodes[idx]
+			if node.Position.Level != defaultInvalidLevel {
+				continue
+			}
+			ready := true
+			for _, depId := range node.IntIdFields.DependsOnIds {
+				depLevel := nodes[depId].Position.Level
+				if depLevel == defaultInvalidLevel || depLevel >= currentLevel {
+					ready = false
+					break
+				}
+			}
+			if ready && (bestId < 0 || labels[idx] > labels[bestId]) {
+				bestId = idx
+			}
+		}
+
+		levelFull := maxNodesPerLevel > 0 && numNodesInLevel >= maxNodesPerLevel
+		if bestId < 0 || levelFull {
+			if numNodesInLevel == 0 {
+				return newLayoutError("", layoutCheckReachability,
+					"no node can be placed in level %v", currentLevel)
+			}
+			currentLevel++
+			numNodesInLevel = 0
+			continue
+		}
+
+		nodes[bestId].Position.Level = currentLevel
+		numNodesInLevel++
+		numPlaced++
+	}
+	return nil
+}
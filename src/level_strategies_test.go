@@ -0,0 +1,107 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+// Create the nodes with the integer id fields filled (see makeTestNodes for the specs)
+func makeTestNodeData(t *testing.T, specs ...string) []NodeData {
+	nodes := make([]NodeData, 0, len(specs))
+	for _, input := range makeTestNodes(specs...) {
+		pushBack(&nodes, NodeData{InputFields: input})
+	}
+	if err := fillIntIdFields(nodes); err != nil {
+		t.Fatalf("unable to fill ids: %v", err)
+	}
+	return nodes
+}
+
+func TestIsLabelListBefore(t *testing.T) {
+	tests := []struct {
+		first  []int
+		second []int
+		want   bool
+	}{
+		{[]int{}, []int{1}, true},
+		{[]int{1}, []int{}, false},
+		{[]int{3, 1}, []int{3, 2}, true},
+		{[]int{3}, []int{2, 9}, false},
+		{[]int{3, 2}, []int{3, 2}, false},
+	}
+	for _, test := range tests {
+		if got := isLabelListBefore(test.first, test.second); got != test.want {
+			t.Errorf("%v before %v: got %v, want %v", test.first, test.second, got, test.want)
+		}
+	}
+}
+
+func TestComputeCoffmanGrahamLabels(t *testing.T) {
+	nodes := makeTestNodeData(t, "a:", "b: a", "c: a", "d: b c")
+	labels, err := computeCoffmanGrahamLabels(nodes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// d has no users. b and c have the same user labels (b is declared first).
+	want := []int{4, 2, 3, 1}
+	if !reflect.DeepEqual(labels, want) {
+		t.Errorf("got %v, want %v", labels, want)
+	}
+}
+
+func TestComputeCoffmanGrahamLevels(t *testing.T) {
+	tests := []struct {
+		name             string
+		specs            []string
+		maxNodesPerLevel int
+		want             []int
+	}{
+		{
+			name:             "no limit",
+			specs:            []string{"a:", "b: a", "c: a", "d: b c"},
+			maxNodesPerLevel: 0,
+			want:             []int{0, 1, 1, 2},
+		},
+		{
+			name:             "one node per level",
+			specs:            []string{"a:", "b: a", "c: a", "d: b c"},
+			maxNodesPerLevel: 1,
+			want:             []int{0, 2, 1, 3},
+		},
+		{
+			name:             "wide level split",
+			specs:            []string{"s0:", "s1:", "s2:", "s3:", "s4:", "s5:"},
+			maxNodesPerLevel: 4,
+			want:             []int{1, 1, 0, 0, 0, 0},
+		},
+		{
+			name:             "node waits for its dependencies",
+			specs:            []string{"a:", "b:", "c:", "d: c"},
+			maxNodesPerLevel: 3,
+			want:             []int{0, 0, 0, 1},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			nodes := makeTestNodeData(t, test.specs...)
+			if err := computeCoffmanGrahamLevels(test.maxNodesPerLevel, nodes); err != nil {
+				t.Fatal(err)
+			}
+			got := make([]int, 0, len(nodes))
+			for idx := range nodes {
+				pushBack(&got, nodes[idx].Position.Level)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestComputeCoffmanGrahamLevelsWithCycle(t *testing.T) {
+	nodes := makeTestNodeData(t, "a: b", "b: a")
+	if err := computeCoffmanGrahamLevels(2, nodes); err == nil {
+		t.Error("expected an error for the cycle")
+	}
+}
@@ -0,0 +1,166 @@
+// This file handles the routing of links that span multiple levels.
+// Without routing, such a link is drawn directly from one node to the other, cutting through the
+// nodes in the levels between them. With algo-config link-routing: waypoints, a waypoint (dummy
+// node) is inserted in every level between them. A waypoint takes a slot in the level like a
+// node, so the space for the link is reserved, and the link is drawn through the waypoints.
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// A point on the board (px)
+type BoardPoint struct {
+	X int
+	Y int
+}
+
+// Virtual node used for routing a link through a level
+type LinkWaypoint struct {
+	Position NodePositionFields
+	// Position of the slot taken by the waypoint (same as the position of a node)
+	LeftPx int
+	TopPx  int
+	// Height of the slot: the tallest node in the level (orientation: vertical)
+	HeightPx int
+}
+
+// All the waypoints of the graph.
+// Waypoints are identified by (number of nodes + index in List) in the levelMap. This way, nodes
+// and waypoints can be handled together in the levelMap.
+type LinkWaypoints struct {
+	// Number of nodes (id of the first waypoint)
+	FirstId int
+	List    []LinkWaypoint
+	// (node id, dependency id) -> waypoint ids, in the order from the node to the dependency
+	ByLink map[[2]int][]int
+}
+
+// Check if the id in the levelMap is for a waypoint
+func (waypoints *LinkWaypoints) isWaypoint(id int) bool {
+	return id >= waypoints.FirstId
+}
+
+// Get the waypoint for the id in the levelMap
+func (waypoints *LinkWaypoints) get(id int) *LinkWaypoint {
+	return &waypoints.List[id-waypoints.FirstId]
+}
+
+// Total number of ids used in the levelMap (nodes and waypoints)
+func (waypoints *LinkWaypoints) numIds() int {
+	return waypoints.FirstId + len(waypoints.List)
+}
+
+// Return the waypoint ids of the link between the given nodes, in the order from the owner to
+// the partner. Nodes can be in any order (ids are swapped for node-sorting: descend).
+func (waypoints *LinkWaypoints) getIdsForLink(ownerId int, partnerId int) []int {
+	if ids, ok := waypoints.ByLink[[2]int{ownerId, partnerId}]; ok {
+		return ids
+	}
+	ids := waypoints.ByLink[[2]int{partnerId, ownerId}]
+	reversed := make([]int, 0, len(ids))
+	for idx := len(ids) - 1; idx >= 0; idx-- {
+		pushBack(&reversed, ids[idx])
+	}
+	return reversed
+}
+
+// Insert waypoints for all the links spanning more than one level (if enabled by algo-config).
+// The waypoints are added at the end of their levels in the levelMap.
+// Must be called before the depends-on and used-by ids are swapped for node sorting.
+func insertLinkWaypoints(algoConfig *AlgoConfigFields, levelMap [][]int,
+	nodes []NodeData) ([][]int, *LinkWaypoints) {
+	waypoints := &LinkWaypoints{FirstId: len(nodes), ByLink: map[[2]int][]int{}}
+	if algoConfig.LinkRouting != "waypoints" {
+		return levelMap, waypoints
+	}
+
+	for idx := range nodes {
+		node := &nodes[idx]
+		for _, depId := range node.IntIdFields.DependsOnIds {
+			startLevel := node.Position.Level
+			endLevel := nodes[depId].Position.Level
+			step := 1
+			if endLevel < startLevel {
+				step = -1
+			}
+
+			ids := make([]int, 0)
+			for level := startLevel + step; level != endLevel; level += step {
+				id := waypoints.numIds()
+				pushBack(&waypoints.List, LinkWaypoint{Position: NodePositionFields{
+					Level: level, Shift: len(levelMap[level])}})
+				pushBack(&levelMap[level], id)
+				pushBack(&ids, id)
+			}
+			if len(ids) > 0 {
+				waypoints.ByLink[[2]int{idx, depId}] = ids
+			}
+		}
+	}
+	return levelMap, waypoints
+}
+
+// Compute the points for drawing the link from the owner node through the waypoints.
+// The link passes every waypoint slot along the level axis (entering from the side facing the
+// owner): vertically, or horizontally with orientation: horizontal.
+func computeWaypointRoute(algoConfig *AlgoConfigFields, displayConfig *DisplayConfigFields,
+	owner *NodeData, partner *NodeData, ids []int, waypoints *LinkWaypoints) []BoardPoint {
+	route := make([]BoardPoint, 0, 2*len(ids))
+	horizontal := algoConfig.Orientation == "horizontal"
+	forward := partner.ElemFields.TopPx > owner.ElemFields.TopPx
+	if horizontal {
+		forward = partner.ElemFields.LeftPx > owner.ElemFields.LeftPx
+	}
+	for _, id := range ids {
+		waypoint := waypoints.get(id)
+		centerX := waypoint.LeftPx + displayConfig.NodeBoxWidthPx/2
+		centerY := waypoint.TopPx + waypoint.HeightPx/2
+		// Entry and exit points for a link going down (or right)
+		entry := BoardPoint{centerX, waypoint.TopPx}
+		exit := BoardPoint{centerX, waypoint.TopPx + waypoint.HeightPx}
+		if horizontal {
+			entry = BoardPoint{waypoint.LeftPx, centerY}
+			exit = BoardPoint{waypoint.LeftPx + displayConfig.NodeBoxWidthPx, centerY}
+		}
+		if forward {
+			route = append(route, entry, exit)
+		} else {
+			route = append(route, exit, entry)
+		}
+	}
+	return route
+}
+
+// Fill the waypoint routes of all the connection dots.
+// Only to be called after computing the positions, and before sorting the dots.
+func fillLinkWaypointRoutes(algoConfig *AlgoConfigFields, displayConfig *DisplayConfigFields,
+	nodes []NodeData, waypoints *LinkWaypoints) {
+	if len(waypoints.List) == 0 {
+		return
+	}
+	for idx := range nodes {
+		node := &nodes[idx]
+		for ii, partnerId := range node.IntIdFields.DependsOnIds {
+			ids := waypoints.getIdsForLink(idx, partnerId)
+			node.ElemFields.DependsOnDots[ii].Waypoints = computeWaypointRoute(algoConfig,
+				displayConfig, node, &nodes[partnerId], ids, waypoints)
+		}
+		for ii, partnerId := range node.IntIdFields.UsedByIds {
+			ids := waypoints.getIdsForLink(idx, partnerId)
+			node.ElemFields.UsedByDots[ii].Waypoints = computeWaypointRoute(algoConfig,
+				displayConfig, node, &nodes[partnerId], ids, waypoints)
+		}
+	}
+}
+
+// Waypoints of the link formatted for the HTML data attribute (eg: "10,20 10,170").
+// Empty if the link has no waypoints.
+func (dot DotElemFields) WaypointsAttr() string {
+	points := make([]string, 0, len(dot.Waypoints))
+	for _, point := range dot.Waypoints {
+		pushBack(&points, fmt.Sprintf("%d,%d", point.X, point.Y))
+	}
+	return strings.Join(points, " ")
+}
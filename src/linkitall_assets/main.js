@@ -9,6 +9,20 @@ let links = []
 let imgWidth = "60%"
 let _buildConfig = null
 
+// Default hue and line style for the well known dependency types.
+// Other types get a hue based on their name (and a solid line).
+const edgeTypeDefaults = {
+    "requires": {hue: 210, style: "solid"},
+    "motivates": {hue: 35, style: "dashed"},
+    "example-of": {hue: 130, style: "dotted"},
+}
+
+// Dash patterns for the line styles
+const edgeStyleDash = {
+    "dashed": {len: 8, gap: 6},
+    "dotted": {len: 2, gap: 4},
+}
+
 // just renaming the function to a simpler one
 function id2el(idstr) {
     return document.getElementById(idstr)
@@ -33,18 +47,30 @@ function removeAndAddClass(elem, className) {
 }
 
 function isImageFile(filename) {
+  // Images inlined in single-file mode
+  if (filename.startsWith("data:image/")) {
+    return true
+  }
   let checkExt = ['.jpg', '.jpeg', '.png', '.gif', '.bmp', '.svg']
   let lowerFilename = filename.toLowerCase()
   return checkExt.some(ext => lowerFilename.endsWith(ext))
 }
 
 function getLinkOptions(source, target, color) {
-    let sourceTop = source.getBoundingClientRect().top
-    let targetTop = target.getBoundingClientRect().top
+    let sourceRect = source.getBoundingClientRect()
+    let targetRect = target.getBoundingClientRect()
 
     let startSocket = 'bottom'
     let endSocket = 'top'
-    if (targetTop < sourceTop) {
+    if (getBuildConfig().Orientation == "horizontal") {
+        // Dots are on the left and right sides of the nodes
+        startSocket = 'right'
+        endSocket = 'left'
+        if (targetRect.left < sourceRect.left) {
+            startSocket = 'left'
+            endSocket = 'right'
+        }
+    } else if (targetRect.top < sourceRect.top) {
         startSocket = 'top'
         endSocket = 'bottom'
     }
@@ -59,6 +85,87 @@ function getLinkOptions(source, target, color) {
     return options
 }
 
+// Get a hue for the dependency type. Same type always gets the same hue.
+function getEdgeTypeHue(edgeType) {
+    if (edgeType in edgeTypeDefaults) {
+        return edgeTypeDefaults[edgeType].hue
+    }
+    let hash = 0
+    for (let idx=0; idx < edgeType.length; idx++) {
+        hash = (hash * 31 + edgeType.charCodeAt(idx)) % 360
+    }
+    return hash
+}
+
+// Options for the link based on the fields of the dependency (label, type, style).
+// These are stored as data attributes on the source dot.
+function getEdgeOptions(dotData, color) {
+    let options = {}
+    let style = dotData.style
+    if (!style && (dotData.type in edgeTypeDefaults)) {
+        style = edgeTypeDefaults[dotData.type].style
+    }
+    if (style in edgeStyleDash) {
+        options.dash = edgeStyleDash[style]
+    }
+    if (dotData.label) {
+        options.middleLabel = LeaderLine.captionLabel(dotData.label, {
+            color,
+            outlineColor: "#222",
+            fontSize: "14px",
+        })
+    }
+    return options
+}
+
+// Parse the waypoints of a link (data-waypoints="x1,y1 x2,y2 ..."). Positions are on the board.
+function parseWaypoints(attr) {
+    if (!attr) {
+        return []
+    }
+    return attr.split(" ").map(pair => {
+        const [x, y] = pair.split(",").map(Number)
+        return {x, y}
+    })
+}
+
+// Draw a link as a chain of segments going through the waypoints.
+// Only the last segment gets the arrow, and only the middle segment gets the label.
+function connectThroughWaypoints(source, target, waypoints, color, dotData) {
+    const board = id2el("board")
+    const ends = [source]
+    for (const point of waypoints) {
+        ends.push(LeaderLine.pointAnchor(board, point))
+    }
+    ends.push(target)
+
+    const linkOptions = getLinkOptions(source, target, color)
+    const edgeOptions = getEdgeOptions(dotData, color)
+    const numSegments = ends.length - 1
+    const labelIdx = Math.floor(numSegments / 2)
+    for (let idx=0; idx < numSegments; idx++) {
+        let options = {color, size: 2, path: "straight", endPlug: "behind"}
+        if (idx == 0) {
+            options.startSocket = linkOptions.startSocket
+            options.path = "fluid"
+        }
+        if (idx == numSegments - 1) {
+            options.endSocket = linkOptions.endSocket
+            options.path = "fluid"
+            delete options.endPlug
+        }
+        if (edgeOptions.dash) {
+            options.dash = edgeOptions.dash
+        }
+        if (idx == labelIdx && edgeOptions.middleLabel) {
+            options.middleLabel = edgeOptions.middleLabel
+        }
+        let link = new LeaderLine(ends[idx], ends[idx+1])
+        link.setOptions(options)
+        links.push(link)
+    }
+}
+
 // Find all the link-source dots and connect them to their target dot.
 function connectDots() {
     // Template used to color links and their dots
@@ -82,22 +189,37 @@ function connectDots() {
             continue
         }
 
-        // Color for this link
-        let color = colorTemplate.replace("{hue}", hue.toString())
+        // Color for this link. Typed links get the color of their type.
+        const dotData = source.dataset
+        let linkHue = hue
         hue = (hue + 67) % 360
+        if (dotData.type) {
+            linkHue = getEdgeTypeHue(dotData.type)
+        }
+        let color = colorTemplate.replace("{hue}", linkHue.toString())
         source.style.backgroundColor = color
         target.style.backgroundColor = color
 
+        // Waypoints are given from the source dot to the target dot
+        let waypoints = parseWaypoints(dotData.waypoints)
+
         let buildConfig = getBuildConfig()
         if (buildConfig.ArrowDirection == "parent2child") {
             // Swap source and target in this case
             let temp = source
             source = target
             target = temp
+            waypoints.reverse()
+        }
+
+        if (waypoints.length > 0) {
+            connectThroughWaypoints(source, target, waypoints, color, dotData)
+            continue
         }
 
         let link = new LeaderLine(source, target)
         link.setOptions(getLinkOptions(source, target, color))
+        link.setOptions(getEdgeOptions(dotData, color))
         links.push(link)
     }
 }
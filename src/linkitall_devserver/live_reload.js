@@ -0,0 +1,49 @@
+// This script is injected into the generated page by the development server (serve mode).
+// It is never part of the generated files.
+// The server sends an event after every build:
+//   - build-ok: the page is reloaded to show the updated graph
+//   - build-error: an overlay with the error message is shown over the (stale) graph
+
+(function() {
+    const eventsPath = "/__linkitall/events"
+    const overlayId = "linkitall-error-overlay"
+
+    function hideErrorOverlay() {
+        const overlay = document.getElementById(overlayId)
+        if (overlay != null) {
+            overlay.remove()
+        }
+    }
+
+    function showErrorOverlay(message) {
+        hideErrorOverlay()
+
+        const overlay = document.createElement("div")
+        overlay.id = overlayId
+        overlay.style.cssText = `
+            position: fixed; top: 0; left: 0; right: 0; bottom: 0; z-index: 100;
+            background-color: #000d; color: #eee; padding: 40px; overflow: auto;`
+        overlay.title = "Click to dismiss"
+        overlay.onclick = hideErrorOverlay
+
+        const heading = document.createElement("div")
+        heading.style.cssText = "font-size: 1.4em; color: #f66; margin-bottom: 20px;"
+        heading.textContent = "Build failed. Showing the last successful build below this."
+
+        const details = document.createElement("pre")
+        details.style.cssText = "white-space: pre-wrap; font-size: 1.1em; line-height: 1.4;"
+        details.textContent = message
+
+        overlay.appendChild(heading)
+        overlay.appendChild(details)
+        document.body.appendChild(overlay)
+    }
+
+    const source = new EventSource(eventsPath)
+    source.addEventListener("build-ok", () => {
+        window.location.reload()
+    })
+    source.addEventListener("build-error", (evt) => {
+        showErrorOverlay(JSON.parse(evt.data).message)
+    })
+})()
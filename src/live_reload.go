@@ -0,0 +1,204 @@
+// This file handles the development server used in serve mode.
+// Build results are pushed to the browser using Server-Sent Events (SSE). The page reloads itself
+// after a successful build and shows the error message after a failed one.
+// The script handling these events is injected into the output file only when it is served by
+// the development server. The generated files never contain it.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Paths used by the development server (not part of the output directory)
+const liveReloadEventsPath = "/__linkitall/events"
+const liveReloadScriptPath = "/__linkitall/live_reload.js"
+
+// Location of the script inside the embedded files
+const liveReloadScriptFile = "linkitall_devserver/live_reload.js"
+
+// An SSE comment is sent with this interval to keep idle connections alive
+const liveReloadKeepAliveInterval = 30 * time.Second
+
+// Result of a single build. Kind is "build-ok" or "build-error".
+type buildEvent struct {
+	Kind    string `json:"kind"`
+	Message string `json:"message"`
+}
+
+// Create build event based on the result of processing
+func newBuildEvent(err error) buildEvent {
+	if err != nil {
+		return buildEvent{"build-error", err.Error()}
+	}
+	return buildEvent{"build-ok", ""}
+}
+
+// Keeps track of all the connected pages and sends build events to them
+type liveReloadBroker struct {
+	mutex   sync.Mutex
+	clients map[chan buildEvent]bool
+	// Last published event. Pages connecting after a failed build need the error message.
+	lastEvent buildEvent
+}
+
+func newLiveReloadBroker() *liveReloadBroker {
+	return &liveReloadBroker{clients: map[chan buildEvent]bool{}}
+}
+
+// Send the event to all the connected pages
+func (broker *liveReloadBroker) publish(event buildEvent) {
+	broker.mutex.Lock()
+	defer broker.mutex.Unlock()
+
+	broker.lastEvent = event
+	for client := range broker.clients {
+		select {
+		case client <- event:
+		default:
+			// The page is not reading events. Not worth blocking the build for it.
+		}
+	}
+}
+
+// Register a new page. Returns the channel for events and the last event published.
+func (broker *liveReloadBroker) subscribe() (chan buildEvent, buildEvent) {
+	broker.mutex.Lock()
+	defer broker.mutex.Unlock()
+
+	client := make(chan buildEvent, 1)
+	broker.clients[client] = true
+	return client, broker.lastEvent
+}
+
+func (broker *liveReloadBroker) unsubscribe(client chan buildEvent) {
+	broker.mutex.Lock()
+	defer broker.mutex.Unlock()
+
+	delete(broker.clients, client)
+}
+
+// Write a single event in SSE format
+func writeBuildEvent(writer http.ResponseWriter, event buildEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(writer, "event: %s\ndata: %s\n\n", event.Kind, data)
+	return err
+}
+
+// Handles the SSE connection from a page
+func (broker *liveReloadBroker) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	flusher, ok := writer.(http.Flusher)
+	if !ok {
+		http.Error(writer, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "text/event-stream")
+	writer.Header().Set("Cache-Control", "no-cache")
+	writer.Header().Set("Connection", "keep-alive")
+	writer.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	client, lastEvent := broker.subscribe()
+	defer broker.unsubscribe(client)
+
+	// A freshly loaded page already shows the latest successful build. Sending build-ok here
+	// would cause a reload loop. Only the errors are relevant.
+	if lastEvent.Kind == "build-error" {
+		if writeBuildEvent(writer, lastEvent) != nil {
+			return
+		}
+		flusher.Flush()
+	}
+
+	keepAlive := time.NewTicker(liveReloadKeepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-request.Context().Done():
+			return
+		case event := <-client:
+			if writeBuildEvent(writer, event) != nil {
+				return
+			}
+		case <-keepAlive.C:
+			if _, err := fmt.Fprintf(writer, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+// Insert the live-reload script tag at the end of the body of the HTML document
+func injectLiveReloadScript(html []byte) []byte {
+	scriptTag := []byte(fmt.Sprintf("<script src=\"%s\"></script>\n", liveReloadScriptPath))
+	idx := bytes.LastIndex(bytes.ToLower(html), []byte("</body>"))
+	if idx < 0 {
+		return append(html, scriptTag...)
+	}
+
+	result := make([]byte, 0, len(html)+len(scriptTag))
+	result = append(result, html[:idx]...)
+	result = append(result, scriptTag...)
+	result = append(result, html[idx:]...)
+	return result
+}
+
+// Serve the output file with the live-reload script injected
+func serveOutputFileWithLiveReload(writer http.ResponseWriter, outFile string) {
+	html, err := os.ReadFile(outFile)
+	if err != nil {
+		http.Error(writer, "output file not available", http.StatusNotFound)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "text/html; charset=utf-8")
+	writer.Header().Set("Cache-Control", "no-store")
+	_, err = writer.Write(injectLiveReloadScript(html))
+	if err != nil {
+		log.Printf("Warning: unable to serve %s. %s", outFile, err)
+	}
+}
+
+func serveLiveReloadScript(writer http.ResponseWriter, request *http.Request) {
+	script, err := embeddedFiles.ReadFile(liveReloadScriptFile)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writer.Header().Set("Content-Type", "text/javascript; charset=utf-8")
+	writer.Write(script)
+}
+
+// Create the handler for the development server. Files are served from outdir.
+// The output file also gets the live-reload script.
+func newDevServerHandler(args *CliArgs, broker *liveReloadBroker) http.Handler {
+	outFileName := filepath.Base(args.OutFile)
+	fileServer := http.FileServer(http.Dir(args.OutputDir))
+
+	mux := http.NewServeMux()
+	mux.Handle(liveReloadEventsPath, broker)
+	mux.HandleFunc(liveReloadScriptPath, serveLiveReloadScript)
+	mux.HandleFunc("/", func(writer http.ResponseWriter, request *http.Request) {
+		name := strings.TrimPrefix(request.URL.Path, "/")
+		if name == outFileName || (name == "" && outFileName == "index.html") {
+			serveOutputFileWithLiveReload(writer, args.OutFile)
+			return
+		}
+		fileServer.ServeHTTP(writer, request)
+	})
+	return mux
+}
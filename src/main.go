@@ -3,9 +3,9 @@
 // Important uncommon shortforms used:
 // GDF - Graph Definition File (usually in YAML)
 
-// TODO: Add an output directory option for this tool. As of now, input directory is the target
-// directory where we will keep all the files. When used in serve mode, we serve files from the
-// input directory.
+// The output directory (--outdir) is the target directory where we keep all the generated files.
+// By default, it is the same as the input directory. When used in serve mode, we serve files from
+// the output directory.
 
 package main
 
@@ -18,34 +18,41 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
-
-	argparse "github.com/alexflint/go-arg"
-	copylib "github.com/otiai10/copy"
 )
 
+// Options used for building the graph (with or without server mode).
+// These are also the flags of the legacy CLI (without a subcommand). The build and serve
+// subcommands are converted to this (see cli_commands.go).
 // Some fields (GraphFile, OutFile) are basepaths (just the filename without dir).
-// For these, full path is attached by the getInputsForProcessing() function.
+// OutputDir defaults to InputDir.
+// For these, full path is attached by the prepareInputsForProcessing() function.
 type CliArgs struct {
 	ServerMode bool   `arg:"-s,--serve" help:"run in edit-update-serve mode"`
 	Release    bool   `arg:"-r,--release" help:"run in release mode"`
 	ServerAddr string `arg:"-l,--listen" default:":8101" help:"listen address in serve mode"`
+	NoInteract bool   `arg:"--no-interactive" help:"do not read commands from stdin in serve mode"`
 	InputDir   string `arg:"-i,--indir,required" help:"path to the input directory"`
 	GraphFile  string `arg:"-g,--graph" default:"graph.yaml" help:"input graph base filename"`
+	OutputDir  string `arg:"-d,--outdir" help:"path to the output directory [default: indir]"`
 	OutFile    string `arg:"-o,--out" default:"index.html" help:"output html base filename"`
 	Overwrite  bool   `arg:"--overwrite" help:"overwrite asset files"`
+	Extract    bool   `arg:"--extract-assets" help:"extract asset and vendor files to outdir and exit"`
+	SingleFile bool   `arg:"--single-file" help:"inline CSS and JS files in the output html"`
+	InlineImgs bool   `arg:"--inline-images" help:"inline small local image resources in the output html"`
 }
 
 var bufferedStdin *bufio.Reader = bufio.NewReader(os.Stdin)
 
 // Return path to the assets directory inside indir
 func getPathToAssetDir(indir string) string {
-	return filepath.Join(indir, "linkitall_assets")
+	return filepath.Join(indir, assetDirName)
 }
 
 // Similar to the above, but for vendor directory
 func getPathToVendorDir(indir string) string {
-	return filepath.Join(indir, "linkitall_vendor")
+	return filepath.Join(indir, vendorDirName)
 }
 
 // Check if the give `path` is accessible.
@@ -79,112 +86,113 @@ func canFileWrite(path string) bool {
 	return true
 }
 
-// Parse arguments and perform steps to prepare input for processing.
+// Perform steps to prepare input for processing.
 // If --indir is specified "?", get the input path from the user via stdin.
-// Final InputDir path is converted to absolute path.
-// Check for existence of indir and graph file.
-func getInputsForProcessing() (CliArgs, error) {
-	var args CliArgs
-	if len(os.Args) == 1 {
-		fmt.Printf("No args. Use --help\n")
-		os.Exit(1)
-	}
-	argparse.MustParse(&args)
-
+// Final InputDir and OutputDir paths are converted to absolute paths.
+// Check for existence of indir and graph file. Outdir is created if required.
+func prepareInputsForProcessing(args *CliArgs) error {
 	if args.InputDir == "?" {
 		fmt.Printf("Enter input directory => ")
 		line, err := bufferedStdin.ReadString('\n')
 		if err != nil {
-			return args, err
+			return err
 		}
 		line = strings.TrimSpace(line)
 		args.InputDir = line
 	}
 
 	if !isPathAccessible(args.InputDir, "dir") {
-		return args, fmt.Errorf("input dir not accessible: %s", args.InputDir)
+		return fmt.Errorf("input dir not accessible: %s", args.InputDir)
 	}
 
 	absInputDir, err := filepath.Abs(args.InputDir)
 	if err != nil {
-		return args, err
+		return err
 	}
 
 	args.InputDir = absInputDir
-	args.GraphFile = filepath.Join(args.InputDir, args.GraphFile)
-	if !isPathAccessible(args.GraphFile, "file") {
-		return args, fmt.Errorf("unable to find graph file: %s", args.GraphFile)
+
+	if len(args.OutputDir) == 0 {
+		args.OutputDir = args.InputDir
 	}
-	// Fill full path to input and output
-	args.OutFile = filepath.Join(args.InputDir, args.OutFile)
-	if !canFileWrite(args.OutFile) {
-		return args, fmt.Errorf("unable to open file for writing: %s", args.OutFile)
+	err = os.MkdirAll(args.OutputDir, 0755)
+	if err != nil {
+		return fmt.Errorf("unable to create output dir: %s", err)
 	}
-
-	return args, nil
-}
-
-// Return path to the parent dir where the executable is.
-func getExecutableDir() (string, error) {
-	execFile, err := os.Executable()
+	absOutputDir, err := filepath.Abs(args.OutputDir)
 	if err != nil {
-		return "", err
+		return err
 	}
+	args.OutputDir = absOutputDir
 
-	execDir := filepath.Dir(execFile)
-	absExecDir, err := filepath.Abs(execDir)
-	if err != nil {
-		return "", err
+	if args.Extract {
+		// Graph and output files are not required for extracting the assets
+		return nil
+	}
+
+	args.GraphFile = filepath.Join(args.InputDir, args.GraphFile)
+	if !isPathAccessible(args.GraphFile, "file") {
+		return fmt.Errorf("unable to find graph file: %s", args.GraphFile)
+	}
+	// Fill full path to input and output
+	args.OutFile = filepath.Join(args.OutputDir, args.OutFile)
+	if !canFileWrite(args.OutFile) {
+		return fmt.Errorf("unable to open file for writing: %s", args.OutFile)
 	}
 
-	return absExecDir, nil
+	return nil
 }
 
 // Copy all the assets files to the target directory where the output will be generated.
-// The asset files (source) are located in the same directory of the executable.
+// The asset files (source) are embedded in the executable.
 func copyAssetsAndVendorFilesToDir(targetDir string, overwrite bool, release bool) error {
-	execDir, err := getExecutableDir()
+	err := extractEmbeddedDirIfRequired(assetDirName, targetDir, overwrite)
 	if err != nil {
 		return err
 	}
 
-	assetPath := getPathToAssetDir(execDir)
-	targetAssetPath := getPathToAssetDir(targetDir)
-	if !overwrite && isPathAccessible(targetAssetPath, "dir") {
-		log.Printf("Asset dir %s already exists. Skipping copying assets\n", targetAssetPath)
-	} else {
-		log.Printf("Copy %s -> %s\n", assetPath, targetAssetPath)
-		copylib.Copy(assetPath, targetAssetPath)
-	}
-
-	vendorPath := getPathToVendorDir(execDir)
-	targetVendorPath := getPathToVendorDir(targetDir)
 	if release {
 		log.Printf("In release mode. Not copying vendor dir")
-	} else if !overwrite && isPathAccessible(targetVendorPath, "dir") {
-		log.Printf("Vendor dir %s already exists. Skipping copying vendor\n", targetVendorPath)
-	} else {
-		log.Printf("Copy %s -> %s\n", vendorPath, targetVendorPath)
-		copylib.Copy(vendorPath, targetVendorPath)
+		return nil
 	}
 
-	return nil
+	return extractEmbeddedDirIfRequired(vendorDirName, targetDir, overwrite)
 }
 
 // ** This is the core function which does all the processing **
 // Process Graph Data File (GDF) and writes the HTML output.
-// The `indir` is also the target dir. Output is generated at the same location.
-// Copy the required asset dir to the `indir` before calling this function.
+// Output is generated at `outdir`. If it is different from `indir`, local resources are copied
+// to `outdir` as well.
+// Copy the required asset dir to the `outdir` before calling this function.
 func processGraphWriteOutput(args *CliArgs) error {
 	log.Printf("Reading graph: %s\n", args.GraphFile)
 	gdfData, readable, err := loadGdf(args.GraphFile)
 	if !readable {
-		log.Fatalf("graph file %s not readable: %s\n", args.GraphFile, err)
+		return fmt.Errorf("graph file %s not readable: %s", args.GraphFile, err)
 	}
 
 	if err != nil {
 		return err
 	}
+	for _, warning := range gdfData.warnings {
+		log.Printf("Warning: %s", warning)
+	}
+
+	if args.InlineImgs {
+		log.Printf("Inlining local image resources\n")
+		err = inlineSmallImageResources(gdfData, args.InputDir)
+		if err != nil {
+			return err
+		}
+	}
+
+	if args.OutputDir != args.InputDir {
+		log.Printf("Copying local resources to %s\n", args.OutputDir)
+		err = publishLocalResources(gdfData, args.InputDir, args.OutputDir)
+		if err != nil {
+			return err
+		}
+	}
 
 	log.Printf("Preparing nodes\n")
 	nodes, err := createComputeAndFillNodeDataList(gdfData)
@@ -195,11 +203,13 @@ func processGraphWriteOutput(args *CliArgs) error {
 
 	log.Printf("Generating template data\n")
 	controlConfig := ControlConfigFields{
-		Release: args.Release,
+		Release:    args.Release,
+		SingleFile: args.SingleFile,
 	}
 	templateData := newTemplateData(gdfData, nodes, controlConfig)
 
-	targetAssetDir := getPathToAssetDir(args.InputDir)
+	// If the template is missing in the asset dir, the embedded one is used.
+	targetAssetDir := getPathToAssetDir(args.OutputDir)
 	templateFile := filepath.Join(targetAssetDir, "template.html")
 
 	log.Printf("Filling template and writing output\n")
@@ -212,37 +222,39 @@ func processGraphWriteOutput(args *CliArgs) error {
 	return nil
 }
 
-// Process the graph file. Print error if any.
-func processAndLogError(args *CliArgs) {
-	err := processGraphWriteOutput(args)
+// Used to make sure only one update runs at a time (stdin and file watcher can trigger updates)
+var processMutex sync.Mutex
+
+// Process the graph file. Print error if any. The error is returned as well.
+// This never stops the program, even if the processing panics (the server must stay alive).
+func processAndLogError(args *CliArgs) (err error) {
+	processMutex.Lock()
+	defer processMutex.Unlock()
+
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("internal error while processing: %v", recovered)
+			log.Printf("Error: %s", err)
+		}
+	}()
+
+	err = processGraphWriteOutput(args)
 
 	if err != nil {
 		log.Printf("Error: %s", err)
 	}
+	return err
 }
 
-// In server mode, we run a http server on the target directory.
-// We also run a read-update cycle to update the output file.
-func runInServerMode(args *CliArgs) {
-	// Run processing once before starting server
-	processAndLogError(args)
-
-	// Start server on the target dir
-	fileServer := http.FileServer(http.Dir(args.InputDir))
-	go func() {
-		log.Printf("Starting server for dir %s. Listening at %s\n",
-			args.InputDir, args.ServerAddr)
-		http.ListenAndServe(args.ServerAddr, fileServer)
-	}()
-
-	time.Sleep(time.Second)
-
-	// Run read-update cycle
+// Run read-update cycle on stdin. Returns when the user quits.
+// If stdin is not available (eg: no TTY), it keeps running with the file watcher alone.
+func runInteractiveCycle(rebuild func()) {
 	for {
 		fmt.Printf("\nq: quit, enter: update output => ")
 		line, err := bufferedStdin.ReadString('\n')
 		if err != nil {
-			log.Fatalf("unable to read from stdin. %s", err)
+			log.Printf("Unable to read from stdin (%s). Continuing without it\n", err)
+			select {}
 		}
 
 		line = strings.TrimSpace(line)
@@ -252,32 +264,90 @@ func runInServerMode(args *CliArgs) {
 			log.Printf("Warning: Ignoring input: '%s'", line)
 			continue
 		}
-		processAndLogError(args)
+		rebuild()
 	}
 }
 
-func main() {
-	args, err := getInputsForProcessing()
+// In server mode, we run a http server on the target directory.
+// The output file is updated whenever the graph file or local resources change. The result of
+// every update is pushed to the pages opened in the browser.
+// Optionally, we also run a read-update cycle on stdin to update the output file.
+func runInServerMode(args *CliArgs) {
+	broker := newLiveReloadBroker()
+	rebuild := func() {
+		err := processAndLogError(args)
+		broker.publish(newBuildEvent(err))
+	}
+
+	// Run processing once before starting server
+	rebuild()
+
+	// Start server on the target dir
+	handler := newDevServerHandler(args, broker)
+	go func() {
+		log.Printf("Starting server for dir %s. Listening at %s\n",
+			args.OutputDir, args.ServerAddr)
+		err := http.ListenAndServe(args.ServerAddr, handler)
+		log.Fatalf("server stopped. %s", err)
+	}()
+
+	var watchedFiles []string
+	getPaths := func() []string {
+		watchedFiles = getWatchedFiles(args, watchedFiles)
+		return watchedFiles
+	}
+	go watchFiles(getPaths, rebuild)
+
+	time.Sleep(time.Second)
+
+	if args.NoInteract {
+		// Nothing else to do. Rebuilds are triggered by the file watcher.
+		select {}
+	}
+	runInteractiveCycle(rebuild)
+}
+
+// Build the graph (once, or in server mode). Assets are copied to the output directory first.
+func runBuildOrServe(args *CliArgs) error {
+	err := prepareInputsForProcessing(args)
 	if err != nil {
-		log.Fatalf("unable to read args. %s", err)
+		return fmt.Errorf("unable to read args. %s", err)
 	}
 
-	// Check if the graph file exists. It is the input file. User only specifies the dir.
+	if args.Extract {
+		// Unlike the regular copy below, vendor files are extracted even for --release.
+		err = copyAssetsAndVendorFilesToDir(args.OutputDir, args.Overwrite, false)
+		if err != nil {
+			return fmt.Errorf("unable to extract asset files to %s. %s", args.OutputDir, err)
+		}
+		return nil
+	}
 
 	// It doesn't matter whether we are running in server mode or not. We always copy the asset
-	// files to the target dir (input dir in this case).
-	err = copyAssetsAndVendorFilesToDir(args.InputDir, args.Overwrite, args.Release)
-	if err != nil {
-		log.Fatalf("unable to copy asset files to %s", args.InputDir)
+	// files to the target dir (output dir). The exception is single-file mode, where the asset
+	// files are inlined in the output file.
+	if !args.SingleFile {
+		err = copyAssetsAndVendorFilesToDir(args.OutputDir, args.Overwrite, args.Release)
+		if err != nil {
+			return fmt.Errorf("unable to copy asset files to %s. %s", args.OutputDir, err)
+		}
 	}
 
 	if args.ServerMode {
-		runInServerMode(&args)
-	} else {
-		err = processGraphWriteOutput(&args)
+		runInServerMode(args)
+		return nil
 	}
-
+	err = processGraphWriteOutput(args)
 	if err != nil {
-		log.Fatalf("error while processing %s", err)
+		return fmt.Errorf("error while processing %s", err)
+	}
+	return nil
+}
+
+func main() {
+	if len(os.Args) == 1 {
+		fmt.Printf("No args. Use --help\n")
+		os.Exit(1)
 	}
+	os.Exit(runCommandLine())
 }
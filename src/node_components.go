@@ -0,0 +1,203 @@
+// This file handles the layout of the disconnected parts (weakly connected components) of the
+// graph, with algo-config component-layout.
+// mixed: all the components share the levels, and the nodes in a level are spread together
+// (default).
+// side-by-side: every component is laid out on its own (with only its own levels), and the
+// components are placed next to each other across the levels (left to right, or top to bottom
+// with orientation: horizontal).
+// grid: like side-by-side, but with component-grid-columns components in a row, and the rows
+// placed one after the other along the levels.
+package main
+
+import (
+	"math"
+)
+
+// Return the component of every id in the levelMap (nodes and waypoints). Components are
+// numbered in the declaration order of their first node. The number of components is returned
+// as well.
+func findComponents(nodes []NodeData, waypoints *LinkWaypoints) ([]int, int) {
+	componentOf := make([]int, waypoints.numIds())
+	for id := range componentOf {
+		componentOf[id] = -1
+	}
+
+	numComponents := 0
+	for startId := range nodes {
+		if componentOf[startId] >= 0 {
+			continue
+		}
+		componentOf[startId] = numComponents
+		stack := []int{startId}
+		for len(stack) > 0 {
+			current := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			linkedIds := append([]int{}, nodes[current].IntIdFields.DependsOnIds...)
+			linkedIds = append(linkedIds, nodes[current].IntIdFields.UsedByIds...)
+			for _, linkedId := range linkedIds {
+				if componentOf[linkedId] < 0 {
+					componentOf[linkedId] = numComponents
+					pushBack(&stack, linkedId)
+				}
+			}
+		}
+		numComponents++
+	}
+
+	// Waypoints belong to the component of their link
+	for link, ids := range waypoints.ByLink {
+		for _, id := range ids {
+			componentOf[id] = componentOf[link[0]]
+		}
+	}
+	return componentOf, numComponents
+}
+
+// Split the levelMap into one levelMap for every component. The order within the levels is
+// kept. Every component gets only the levels from its lowest to its highest level, so that it
+// is laid out like a graph of its own (the levels of the nodes are not changed).
+func splitLevelMapByComponent(levelMap [][]int, nodes []NodeData,
+	waypoints *LinkWaypoints) [][][]int {
+	componentOf, numComponents := findComponents(nodes, waypoints)
+	result := make([][][]int, numComponents)
+	for component := range result {
+		result[component] = make([][]int, len(levelMap))
+		for level := range levelMap {
+			result[component][level] = make([]int, 0, defaultCapacity)
+		}
+	}
+	for level, ids := range levelMap {
+		for _, id := range ids {
+			component := componentOf[id]
+			pushBack(&result[component][level], id)
+		}
+	}
+
+	for component, componentLevelMap := range result {
+		first, last := 0, len(componentLevelMap)-1
+		for first < last && len(componentLevelMap[first]) == 0 {
+			first++
+		}
+		for last > first && len(componentLevelMap[last]) == 0 {
+			last--
+		}
+		result[component] = componentLevelMap[first : last+1]
+	}
+	return result
+}
+
+// Area taken by a component on the board (px)
+type componentBox struct {
+	left   int
+	top    int
+	right  int
+	bottom int
+}
+
+// Return the area taken by the nodes and waypoints in the levelMap
+func getComponentBox(displayConfig *DisplayConfigFields, levelMap [][]int, nodes []NodeData,
+	waypoints *LinkWaypoints) componentBox {
+	box := componentBox{math.MaxInt32, math.MaxInt32, math.MinInt32, math.MinInt32}
+	for _, ids := range levelMap {
+		for _, id := range ids {
+			left, top, heightPx := 0, 0, 0
+			if waypoints.isWaypoint(id) {
+				waypoint := waypoints.get(id)
+				left, top, heightPx = waypoint.LeftPx, waypoint.TopPx, waypoint.HeightPx
+			} else {
+				elemFields := &nodes[id].ElemFields
+				left, top, heightPx = elemFields.LeftPx, elemFields.TopPx, elemFields.HeightPx
+			}
+			if left < box.left {
+				box.left = left
+			}
+			if top < box.top {
+				box.top = top
+			}
+			if left+displayConfig.NodeBoxWidthPx > box.right {
+				box.right = left + displayConfig.NodeBoxWidthPx
+			}
+			if top+heightPx > box.bottom {
+				box.bottom = top + heightPx
+			}
+		}
+	}
+	return box
+}
+
+// Move all the nodes and waypoints in the levelMap by the given offsets (px)
+func moveComponent(levelMap [][]int, nodes []NodeData, waypoints *LinkWaypoints,
+	leftOffset int, topOffset int) {
+	for _, ids := range levelMap {
+		for _, id := range ids {
+			if waypoints.isWaypoint(id) {
+				waypoint := waypoints.get(id)
+				waypoint.LeftPx += leftOffset
+				waypoint.TopPx += topOffset
+				continue
+			}
+			nodes[id].ElemFields.LeftPx += leftOffset
+			nodes[id].ElemFields.TopPx += topOffset
+		}
+	}
+}
+
+// Place the components (already laid out on their own) next to each other, with
+// component-gap-px between them. With side-by-side, all the components are in a single row and
+// are aligned at their first level (bottom, or left with orientation: horizontal).
+// A row is across the levels: left to right, or top to bottom with orientation: horizontal.
+func packComponents(algoConfig *AlgoConfigFields, displayConfig *DisplayConfigFields,
+	componentLevelMaps [][][]int, nodes []NodeData, waypoints *LinkWaypoints) {
+	numColumns := len(componentLevelMaps)
+	if algoConfig.ComponentLayout == "grid" {
+		numColumns = algoConfig.ComponentGridColumns
+		if numColumns == 0 {
+			numColumns = int(math.Ceil(math.Sqrt(float64(len(componentLevelMaps)))))
+		}
+	}
+	horizontal := algoConfig.Orientation == "horizontal"
+	gapPx := *displayConfig.ComponentGapPx
+
+	boxes := make([]componentBox, 0, len(componentLevelMaps))
+	maxHeightPx := 0
+	for _, componentLevelMap := range componentLevelMaps {
+		box := getComponentBox(displayConfig, componentLevelMap, nodes, waypoints)
+		if box.bottom-box.top > maxHeightPx {
+			maxHeightPx = box.bottom - box.top
+		}
+		pushBack(&boxes, box)
+	}
+
+	// Position of the current component across the levels, and of the current row
+	crossPx := 0
+	rowPx := 0
+	rowSizePx := 0
+	for idx, componentLevelMap := range componentLevelMaps {
+		if idx > 0 && idx%numColumns == 0 {
+			crossPx = 0
+			rowPx += rowSizePx + gapPx
+			rowSizePx = 0
+		}
+
+		box := boxes[idx]
+		// Sizes across and along the levels, and the offsets to move the component
+		crossSizePx, levelSizePx := box.right-box.left, box.bottom-box.top
+		leftOffset, topOffset := crossPx-box.left, maxHeightPx-box.bottom
+		if numColumns < len(componentLevelMaps) {
+			topOffset = rowPx - box.top
+		}
+		if horizontal {
+			crossSizePx, levelSizePx = levelSizePx, crossSizePx
+			leftOffset, topOffset = -box.left, crossPx-box.top
+			if numColumns < len(componentLevelMaps) {
+				leftOffset = rowPx - box.left
+			}
+		}
+		moveComponent(componentLevelMap, nodes, waypoints, leftOffset, topOffset)
+
+		crossPx += crossSizePx + gapPx
+		if levelSizePx > rowSizePx {
+			rowSizePx = levelSizePx
+		}
+	}
+}
@@ -13,6 +13,41 @@ import (
 const defaultCapacity = 5
 const defaultInvalidLevel = -1
 
+// Kinds of checks done during the layout computation (used in LayoutError)
+const (
+	layoutCheckNodeIds      = "node-ids"
+	layoutCheckStrategy     = "strategy"
+	layoutCheckReachability = "reachability"
+	layoutCheckChildLevel   = "child-level"
+	layoutCheckParentLevel  = "parent-level"
+	layoutCheckLevelValue   = "level-value"
+	layoutCheckLevelMap     = "level-map"
+	layoutCheckLevelHints   = "level-hints"
+)
+
+// Error found during the layout computation.
+// Some of these indicate a bug in the code, but the user input can also lead to them.
+type LayoutError struct {
+	// Name of the node where the check failed. Empty if it is not about a specific node.
+	Node string
+	// Kind of check that failed (one of layoutCheck* values)
+	Check string
+	// Details of the failure
+	Message string
+}
+
+func (err *LayoutError) Error() string {
+	if len(err.Node) == 0 {
+		return fmt.Sprintf("layout error (%s): %s", err.Check, err.Message)
+	}
+	return fmt.Sprintf("layout error (%s) in node '%s': %s", err.Check, err.Node, err.Message)
+}
+
+// Constructor for LayoutError. Message is formatted like fmt.Sprintf.
+func newLayoutError(node string, check string, format string, args ...any) *LayoutError {
+	return &LayoutError{node, check, fmt.Sprintf(format, args...)}
+}
+
 // All the fields related to defining IDs for node and connections
 type NodeIntIdFields struct {
 	// Assign unique integer ID to every node. This is just the index
@@ -41,6 +76,11 @@ type DotElemFields struct {
 	PartnerNodeId string
 	// only used for sorting
 	LinkAngle float64
+	// Fields of the link from the depends-on entry (label, type, style)
+	Link DependencyFields
+	// Points for routing the link through the levels in between (from this node to the
+	// partner). Empty if the link is drawn directly.
+	Waypoints []BoardPoint
 }
 
 // Used to allow sorting of DotElemFields using to untangle links.
@@ -77,6 +117,8 @@ type NodeElemFields struct {
 	LeftPx int
 	// Top edge position (px)
 	TopPx int
+	// Height of the node (px)
+	HeightPx int
 	// Link to the associated resource
 	Link string
 }
@@ -116,7 +158,7 @@ func fillIntIdFields(nodeDataSeq []NodeData) error {
 	for idx := range nodeDataSeq {
 		node := &nodeDataSeq[idx]
 		if _, found := nodeName2Id[node.InputFields.Name]; found {
-			return fmt.Errorf("node name repeated '%v'", node.InputFields.Name)
+			return newLayoutError(node.InputFields.Name, layoutCheckNodeIds, "node name repeated")
 		}
 		nodeName2Id[node.InputFields.Name] = idx
 		node.IntIdFields.Uid = idx
@@ -128,10 +170,12 @@ func fillIntIdFields(nodeDataSeq []NodeData) error {
 	// Fill DependsOnIds and UsedByIds using nodeName2Id
 	for idx := range nodeDataSeq {
 		node := &nodeDataSeq[idx]
-		for _, depNodeName := range node.InputFields.DependsOn {
+		for _, dep := range node.InputFields.DependsOn {
+			depNodeName := dep.Name
 			depNodeId, ok := nodeName2Id[depNodeName]
 			if !ok {
-				return fmt.Errorf("dependency not found '%v'", depNodeName)
+				return newLayoutError(node.InputFields.Name, layoutCheckNodeIds,
+					"dependency not found '%v'", depNodeName)
 			}
 			pushBack(&node.IntIdFields.DependsOnIds, depNodeId)
 			depNode := &nodeDataSeq[depNodeId]
@@ -142,6 +186,40 @@ func fillIntIdFields(nodeDataSeq []NodeData) error {
 	return nil
 }
 
+// Remove the dependencies which are reached through other dependencies of the same node
+// (algo-config transitive-reduction), so that the links for them are not drawn.
+// Must be called right after fillIntIdFields.
+func applyTransitiveReduction(algoConfig *AlgoConfigFields, gdfNodes []NodeInputFields,
+	nodeDataSeq []NodeData) {
+	if !algoConfig.TransitiveReduction {
+		return
+	}
+
+	redundant := map[[2]int]bool{}
+	for _, item := range findRedundantDependencies(gdfNodes) {
+		redundant[[2]int{item.nodeIdx, item.depIdx}] = true
+	}
+	if len(redundant) == 0 {
+		return
+	}
+
+	for idx := range nodeDataSeq {
+		nodeDataSeq[idx].IntIdFields.UsedByIds = make([]int, 0, defaultCapacity)
+	}
+	for idx := range nodeDataSeq {
+		node := &nodeDataSeq[idx]
+		dependsOnIds := make([]int, 0, len(node.IntIdFields.DependsOnIds))
+		for depIdx, depNodeId := range node.IntIdFields.DependsOnIds {
+			if redundant[[2]int{idx, depIdx}] {
+				continue
+			}
+			pushBack(&dependsOnIds, depNodeId)
+			pushBack(&nodeDataSeq[depNodeId].IntIdFields.UsedByIds, idx)
+		}
+		node.IntIdFields.DependsOnIds = dependsOnIds
+	}
+}
+
 // Initialization step of computeLevels algorithm.
 // Assign level 0 to all nodes without the specified linked nodes.
 // Everyone else gets an invalid level.
@@ -150,7 +228,7 @@ func fillIntIdFields(nodeDataSeq []NodeData) error {
 //
 //	For bottom2top -> DependsOnIds
 //	For top2bottom -> UsedByIds
-func initializeForComputeLevels(strategy string, nodes []NodeData, level0NodeIds *[]int) {
+func initializeForComputeLevels(strategy string, nodes []NodeData, level0NodeIds *[]int) error {
 	for idx := range nodes {
 		node := &nodes[idx]
 		// We set an invalid value here. This will be useful when checking if all nodes received
@@ -164,7 +242,8 @@ func initializeForComputeLevels(strategy string, nodes []NodeData, level0NodeIds
 		case "top2bottom":
 			level0DecisionCount = len(node.IntIdFields.UsedByIds)
 		default:
-			panic("unknown strategy for level initialization")
+			return newLayoutError("", layoutCheckStrategy,
+				"unknown strategy for level initialization '%v'", strategy)
 		}
 		if level0DecisionCount == 0 {
 			// No dependencies -> level 0 (absolute bottom)
@@ -172,11 +251,12 @@ func initializeForComputeLevels(strategy string, nodes []NodeData, level0NodeIds
 			pushBack(level0NodeIds, idx)
 		}
 	}
+	return nil
 }
 
 // Process a single node in the computeLevels function.
 func processNodeComputeLevels(strategy string, node *NodeData, nodes []NodeData,
-	nextLevelNodeIds *[]int) {
+	nextLevelNodeIds *[]int) error {
 	nextLevel := node.Position.Level + 1
 
 	var linkedNodeIds []int
@@ -186,7 +266,8 @@ func processNodeComputeLevels(strategy string, node *NodeData, nodes []NodeData,
 	case "top2bottom":
 		linkedNodeIds = node.IntIdFields.DependsOnIds
 	default:
-		panic("unknown strategy for level initialization")
+		return newLayoutError(node.InputFields.Name, layoutCheckStrategy,
+			"unknown strategy for level computation '%v'", strategy)
 	}
 
 	for _, linkedNodeId := range linkedNodeIds {
@@ -194,14 +275,19 @@ func processNodeComputeLevels(strategy string, node *NodeData, nodes []NodeData,
 		linkedNode.Position.Level = nextLevel
 		pushBack(nextLevelNodeIds, linkedNodeId)
 	}
+	return nil
 }
 
 // Go over all the current nodes and process them
 func processCurrentNodesComputeLevels(strategy string, nodes []NodeData, currentLevelNodeIds []int,
-	nextLevelNodeIds *[]int) {
+	nextLevelNodeIds *[]int) error {
 	for _, nodeId := range currentLevelNodeIds {
-		processNodeComputeLevels(strategy, &nodes[nodeId], nodes, nextLevelNodeIds)
+		err := processNodeComputeLevels(strategy, &nodes[nodeId], nodes, nextLevelNodeIds)
+		if err != nil {
+			return err
+		}
 	}
+	return nil
 }
 
 // Validate the result of computeLevels
@@ -217,7 +303,8 @@ func validateComputeLevels(strategy string, nodes []NodeData) error {
 	for _, node := range nodes {
 		// Every node must have a valid level
 		if node.Position.Level == defaultInvalidLevel {
-			return fmt.Errorf("unreachable node '%v'", node.InputFields.Name)
+			return newLayoutError(node.InputFields.Name, layoutCheckReachability,
+				"unreachable node")
 		}
 
 		// Every child must be at least 1 level above the current node
@@ -225,10 +312,9 @@ func validateComputeLevels(strategy string, nodes []NodeData) error {
 		for _, childNodeId := range node.IntIdFields.UsedByIds {
 			childNode := &nodes[childNodeId]
 			if childNode.Position.Level < expectedMinLevelForChildren {
-				// Shows a bug in the code
-				panic(fmt.Sprintf("Child node level %v < expected level %v (child %v, parent %v)",
-					childNode.Position.Level, expectedMinLevelForChildren,
-					childNode.InputFields.Name, node.InputFields.Name))
+				return newLayoutError(childNode.InputFields.Name, layoutCheckChildLevel,
+					"child node level %v < expected level %v (parent %v)",
+					childNode.Position.Level, expectedMinLevelForChildren, node.InputFields.Name)
 			}
 		}
 
@@ -237,10 +323,9 @@ func validateComputeLevels(strategy string, nodes []NodeData) error {
 		for _, parentNodeId := range node.IntIdFields.DependsOnIds {
 			parentNode := &nodes[parentNodeId]
 			if parentNode.Position.Level > expectedMaxLevelForParent {
-				// Shows a bug in the code
-				panic(fmt.Sprintf("Parent node level %v > expected level %v (child %v, parent %v)",
-					parentNode.Position.Level, expectedMaxLevelForParent,
-					node.InputFields.Name, parentNode.InputFields.Name))
+				return newLayoutError(parentNode.InputFields.Name, layoutCheckParentLevel,
+					"parent node level %v > expected level %v (child %v)",
+					parentNode.Position.Level, expectedMaxLevelForParent, node.InputFields.Name)
 			}
 		}
 
@@ -254,9 +339,9 @@ func validateComputeLevels(strategy string, nodes []NodeData) error {
 				}
 			}
 			if node.Position.Level != maxParentLevel+1 {
-				// Shows a bug in the code
-				panic(fmt.Sprintf("For node %v, mismatch in level. Got %v, expected %v",
-					node.InputFields.Name, node.Position.Level, maxParentLevel+1))
+				return newLayoutError(node.InputFields.Name, layoutCheckLevelValue,
+					"mismatch in level. Got %v, expected %v",
+					node.Position.Level, maxParentLevel+1)
 			}
 		} else if strategy == "top2bottom" {
 			// A nodes level = min(level of all children) - 1
@@ -268,9 +353,9 @@ func validateComputeLevels(strategy string, nodes []NodeData) error {
 				}
 			}
 			if node.Position.Level != minChildLevel-1 {
-				// Shows a bug in the code
-				panic(fmt.Sprintf("For node %v, mismatch in level. Got %v, expected %v",
-					node.InputFields.Name, node.Position.Level, minChildLevel-1))
+				return newLayoutError(node.InputFields.Name, layoutCheckLevelValue,
+					"mismatch in level. Got %v, expected %v",
+					node.Position.Level, minChildLevel-1)
 			}
 		}
 	}
@@ -308,15 +393,17 @@ func reverseNodeLevels(nodes []NodeData) {
 //			keep a list of these and use as the starting point of next iteration
 //
 // Maximum number of iterations = number of nodes.
-// At the end, perform sanity checks on the code (and coder)
-func computeLevels(algoConfig *AlgoConfigFields, nodes []NodeData) error {
-	strategy := algoConfig.LevelStrategy
+// Used for the strategies bottom2top and top2bottom.
+func computeLongestPathLevels(strategy string, nodes []NodeData) error {
 	var currentLevelNodeIds []int
 	nextLevelNodeIds := make([]int, 0, defaultCapacity)
 
-	initializeForComputeLevels(strategy, nodes, &nextLevelNodeIds)
+	err := initializeForComputeLevels(strategy, nodes, &nextLevelNodeIds)
+	if err != nil {
+		return err
+	}
 	if len(nextLevelNodeIds) == 0 {
-		return fmt.Errorf("found no level 0 nodes")
+		return newLayoutError("", layoutCheckReachability, "found no level 0 nodes")
 	}
 
 	// In the worse case, every node get a unique level.
@@ -331,7 +418,11 @@ func computeLevels(algoConfig *AlgoConfigFields, nodes []NodeData) error {
 		currentLevelNodeIds = nextLevelNodeIds
 		nextLevelNodeIds = make([]int, 0, defaultCapacity)
 
-		processCurrentNodesComputeLevels(strategy, nodes, currentLevelNodeIds, &nextLevelNodeIds)
+		err = processCurrentNodesComputeLevels(strategy, nodes, currentLevelNodeIds,
+			&nextLevelNodeIds)
+		if err != nil {
+			return err
+		}
 		nextLevelNodeIds = getUnique(nextLevelNodeIds)
 	}
 
@@ -339,12 +430,41 @@ func computeLevels(algoConfig *AlgoConfigFields, nodes []NodeData) error {
 		// In this strategy, we get the node levels reversed. We have to reverse the levels.
 		reverseNodeLevels(nodes)
 	}
+	return nil
+}
+
+// Compute level for all the nodes based on the level strategy.
+// At the end, perform sanity checks on the code (and coder)
+func computeLevels(algoConfig *AlgoConfigFields, nodes []NodeData) error {
+	strategy := algoConfig.LevelStrategy
+	var err error
+	if strategy == "coffman-graham" {
+		err = computeCoffmanGrahamLevels(algoConfig.MaxNodesPerLevel, nodes)
+	} else {
+		err = computeLongestPathLevels(strategy, nodes)
+	}
+	if err != nil {
+		return err
+	}
 
-	err := validateComputeLevels(strategy, nodes)
+	err = validateComputeLevels(strategy, nodes)
 	if err != nil {
 		return err
 	}
 
+	applied, err := applyLevelHints(nodes)
+	if err != nil {
+		return err
+	}
+	if applied {
+		// Hints move the nodes away from the levels given by the strategy. Only the generic
+		// checks are valid after that.
+		err = validateComputeLevels("", nodes)
+		if err != nil {
+			return err
+		}
+	}
+
 	if algoConfig.NodeSorting == "descend" {
 		reverseNodeLevels(nodes)
 	}
@@ -352,12 +472,76 @@ func computeLevels(algoConfig *AlgoConfigFields, nodes []NodeData) error {
 	return nil
 }
 
+// Move the nodes to the levels given by the level hints (level, same-level-as). Other nodes
+// are moved up only as required by their dependencies.
+// Returns false if there are no hints (nothing is done in that case).
+func applyLevelHints(nodes []NodeData) (bool, error) {
+	inputs := make([]NodeInputFields, 0, len(nodes))
+	for idx := range nodes {
+		pushBack(&inputs, nodes[idx].InputFields)
+	}
+	if !hasLevelHints(inputs) {
+		return false, nil
+	}
+
+	bounds, diags := computeLevelHintBounds(inputs)
+	if len(diags) > 0 {
+		return true, newLayoutError(diags[0].Node, layoutCheckLevelHints, "%s", diags[0].Message)
+	}
+
+	// Start from the levels given by the strategy, limited to the bounds
+	for idx := range nodes {
+		node := &nodes[idx]
+		group := bounds.group[node.InputFields.Name]
+		if node.Position.Level < bounds.minLevel[group] {
+			node.Position.Level = bounds.minLevel[group]
+		}
+		if node.Position.Level > bounds.maxLevel[group] {
+			node.Position.Level = bounds.maxLevel[group]
+		}
+	}
+
+	// Raise the levels until every node is above its dependencies and the nodes in the same
+	// group share the level. Levels stay within the bounds, so this ends quickly.
+	maxRounds := 2*len(nodes) + 2
+	for round := 0; ; round++ {
+		if round > maxRounds {
+			return true, newLayoutError("", layoutCheckLevelHints, "unable to apply level hints")
+		}
+		changed := false
+		groupLevel := map[string]int{}
+		for idx := range nodes {
+			group := bounds.group[nodes[idx].InputFields.Name]
+			if nodes[idx].Position.Level > groupLevel[group] {
+				groupLevel[group] = nodes[idx].Position.Level
+			}
+		}
+		for idx := range nodes {
+			node := &nodes[idx]
+			level := groupLevel[bounds.group[node.InputFields.Name]]
+			for _, depId := range node.IntIdFields.DependsOnIds {
+				if nodes[depId].Position.Level+1 > level {
+					level = nodes[depId].Position.Level + 1
+				}
+			}
+			if level != node.Position.Level {
+				node.Position.Level = level
+				changed = true
+			}
+		}
+		if !changed {
+			return true, nil
+		}
+	}
+}
+
 // Compute shifts - This is straightforward. For every level, we go from left to right.
 // We can also compute levelMap with this function.
-func computeShiftsAndGetLevelMap(nodes []NodeData) [][]int {
+// Levels without nodes are allowed only if allowEmptyLevels is true (levels given by hints).
+func computeShiftsAndGetLevelMap(nodes []NodeData, allowEmptyLevels bool) ([][]int, error) {
 	levelMap := make([][]int, 0)
 	if len(nodes) == 0 {
-		return levelMap
+		return levelMap, nil
 	}
 
 	// find maxLevel
@@ -369,8 +553,7 @@ func computeShiftsAndGetLevelMap(nodes []NodeData) [][]int {
 	}
 
 	if maxLevel < 0 {
-		// shows a bug in code
-		panic("Unable to find maxLevel")
+		return levelMap, newLayoutError("", layoutCheckLevelMap, "unable to find max level")
 	}
 
 	// Initialize levelMap for each level
@@ -388,13 +571,13 @@ func computeShiftsAndGetLevelMap(nodes []NodeData) [][]int {
 	}
 
 	// Sanity check:
-	for level := 0; level <= maxLevel; level++ {
+	for level := 0; level <= maxLevel && !allowEmptyLevels; level++ {
 		if len(levelMap[level]) == 0 {
-			panic(fmt.Sprintf("Level %v has 0 nodes", level))
+			return levelMap, newLayoutError("", layoutCheckLevelMap, "level %v has 0 nodes", level)
 		}
 	}
 
-	return levelMap
+	return levelMap, nil
 }
 
 // Used to convert numeric IDs to string IDs used by HTML elements
@@ -416,7 +599,25 @@ func buildDotElemFields(prefix string, ownerId int, partnerId int) DotElemFields
 		second = ownerIdStr
 	}
 	dotElemId := fmt.Sprintf("%s_%s_%s", prefix, first, second)
-	return DotElemFields{dotElemId, partnerIdStr, 0}
+	return DotElemFields{dotElemId, partnerIdStr, 0, DependencyFields{}, nil}
+}
+
+// Find the depends-on entry for the link between the given nodes. Nodes can be in any order,
+// since the depends-on and used-by ids are swapped for node-sorting: descend.
+func getDependencyFieldsForLink(nodes []NodeData, firstId int, secondId int) DependencyFields {
+	first := &nodes[firstId].InputFields
+	second := &nodes[secondId].InputFields
+	for _, dep := range first.DependsOn {
+		if dep.Name == second.Name {
+			return dep
+		}
+	}
+	for _, dep := range second.DependsOn {
+		if dep.Name == first.Name {
+			return dep
+		}
+	}
+	return DependencyFields{}
 }
 
 // Fill HTML element IDs in string form
@@ -428,13 +629,15 @@ func buildDotElemFields(prefix string, ownerId int, partnerId int) DotElemFields
 // It is used by the second node N00003). Note that the order of nodes is reversed for
 // the UsedBy connection dot. This makes it easy to map one dot to another when making
 // connections. It is always the node on the top that comes first.
-func fillElemIds(node *NodeData) {
+// The depends-on dots also carry the fields of the link (label, type, style).
+func fillElemIds(node *NodeData, nodes []NodeData) {
 	nodeElemId := formatIntId(node.IntIdFields.Uid)
 	node.ElemFields.NodeElemId = nodeElemId
 
 	node.ElemFields.DependsOnDots = make([]DotElemFields, 0)
 	for _, dependsOnId := range node.IntIdFields.DependsOnIds {
 		dotElemFields := buildDotElemFields("D", node.IntIdFields.Uid, dependsOnId)
+		dotElemFields.Link = getDependencyFieldsForLink(nodes, node.IntIdFields.Uid, dependsOnId)
 		pushBack(&node.ElemFields.DependsOnDots, dotElemFields)
 	}
 
@@ -448,14 +651,16 @@ func fillElemIds(node *NodeData) {
 // Fill HTML element IDs for all the nodes
 func fillElemIdsForAllNodes(nodes []NodeData) {
 	for idx := range nodes {
-		fillElemIds(&nodes[idx])
+		fillElemIds(&nodes[idx], nodes)
 	}
 }
 
 // Each node gets a position, which will be set based on inline CSS.
 // It is a bit tricky since we want to center the alignment.
-func computeNodePositionsAndUpdate(displayConfig *DisplayConfigFields,
-	levelMap [][]int, nodes []NodeData) {
+// Waypoints in the levelMap get a position just like the nodes.
+func computeNodePositionsAndUpdate(algoConfig *AlgoConfigFields,
+	displayConfig *DisplayConfigFields, levelMap [][]int, nodes []NodeData,
+	waypoints *LinkWaypoints) {
 
 	// To be used to calculate max shift and center aligning
 	maxNodesPerLevel := 0
@@ -469,26 +674,48 @@ func computeNodePositionsAndUpdate(displayConfig *DisplayConfigFields,
 		return
 	}
 
-	maxLevel := len(levelMap) - 1
-	horScale := displayConfig.HorizontalStepPx
-	levelHorScale := horScale
+	levelOffsets := computeLevelOffsetsPx(algoConfig, displayConfig, levelMap, nodes, waypoints)
+	crossScale := getCrossStepPx(algoConfig, displayConfig, nodes)
+	levelCrossScale := crossScale
 	centering := 0
 	// We can use levelMap to initialize the positions of nodes
 	for level, nodeIdsForLevel := range levelMap {
+		if len(nodeIdsForLevel) == 0 {
+			// Only possible with level hints
+			continue
+		}
 		if len(nodeIdsForLevel) == maxNodesPerLevel {
-			levelHorScale = horScale
+			levelCrossScale = crossScale
 			centering = 0
 		} else {
 			ratio := float64(maxNodesPerLevel-1) / float64(len(nodeIdsForLevel))
-			levelHorScaleF64 := ratio * float64(horScale)
-			levelHorScale = int(levelHorScaleF64)
-			centering = int(levelHorScaleF64 / 2)
+			levelCrossScaleF64 := ratio * float64(crossScale)
+			levelCrossScale = int(levelCrossScaleF64)
+			centering = int(levelCrossScaleF64 / 2)
 		}
 		for shift, nodeId := range nodeIdsForLevel {
+			// Centering shift (horizontal, unless the orientation is horizontal)
+			leftPx := shift*levelCrossScale + centering
+			topPx := levelOffsets[level]
+			if algoConfig.Orientation == "horizontal" {
+				leftPx = levelOffsets[level]
+				topPx = shift*levelCrossScale + centering
+			}
+			if waypoints.isWaypoint(nodeId) {
+				waypoint := waypoints.get(nodeId)
+				waypoint.LeftPx = leftPx
+				waypoint.TopPx = topPx
+				// The slot of the waypoint spans the tallest node in the level
+				waypoint.HeightPx = displayConfig.NodeBoxHeightPx
+				if algoConfig.Orientation != "horizontal" {
+					waypoint.HeightPx = getMaxNodeHeightPx(displayConfig, nodeIdsForLevel,
+						nodes, waypoints)
+				}
+				continue
+			}
 			node := &nodes[nodeId]
-			// Horizontal centering shift
-			node.ElemFields.LeftPx = shift*levelHorScale + centering
-			node.ElemFields.TopPx = (maxLevel - level) * displayConfig.VerticalStepPx
+			node.ElemFields.LeftPx = leftPx
+			node.ElemFields.TopPx = topPx
 		}
 	}
 }
@@ -501,23 +728,60 @@ func computeLinkAngle(node1 *NodeData, node2 *NodeData) float64 {
 	return angle
 }
 
+// Compute angle of the link from the center of the node to a point on the board
+func computeLinkAngleToPoint(displayConfig *DisplayConfigFields, node *NodeData,
+	point BoardPoint) float64 {
+	hdiff := point.X - (node.ElemFields.LeftPx + displayConfig.NodeBoxWidthPx/2)
+	vdiff := point.Y - (node.ElemFields.TopPx + node.ElemFields.HeightPx/2)
+	return math.Atan2(float64(vdiff), float64(hdiff))
+}
+
+// Compute angle of the link on the side of node1. If the link has waypoints, the angle to the
+// first waypoint is used.
+func computeLinkAngleForDot(displayConfig *DisplayConfigFields, node1 *NodeData,
+	node2 *NodeData, dot *DotElemFields) float64 {
+	if len(dot.Waypoints) > 0 {
+		return computeLinkAngleToPoint(displayConfig, node1, dot.Waypoints[0])
+	}
+	return computeLinkAngle(node1, node2)
+}
+
+// Convert the angle of a link to the angle from the horizontal axis, in the range -pi/2 to pi/2
+// (top to bottom). Used for sorting the dots on the left and right sides of the nodes.
+func getSideLinkAngle(angle float64) float64 {
+	return math.Atan2(math.Sin(angle), math.Abs(math.Cos(angle)))
+}
+
 // We want to adjust the order of connection dots to minimize the amount of link crossings.
 // We do this by sorting them according to their angle one way or another.
+// With orientation: horizontal, the dots are on the sides and are sorted from top to bottom.
 // Only to be called after computing the position!
-func sortDotsToUntangleLinks(nodes []NodeData) {
+func sortDotsToUntangleLinks(algoConfig *AlgoConfigFields, displayConfig *DisplayConfigFields,
+	nodes []NodeData) {
+	horizontal := algoConfig.Orientation == "horizontal"
 	// Fill the LinkAngle for all nodes (dependson and usedby)
 	for idx := range nodes {
 		node1 := &nodes[idx]
 
 		for ii, id2 := range node1.IntIdFields.DependsOnIds {
-			node2 := &nodes[id2]
-			node1.ElemFields.DependsOnDots[ii].LinkAngle = -computeLinkAngle(node1, node2)
+			dot := &node1.ElemFields.DependsOnDots[ii]
+			angle := computeLinkAngleForDot(displayConfig, node1, &nodes[id2], dot)
+			if horizontal {
+				dot.LinkAngle = getSideLinkAngle(angle)
+			} else {
+				dot.LinkAngle = -angle
+			}
 		}
 		sort.Sort(LinkDots(node1.ElemFields.DependsOnDots))
 
 		for ii, id2 := range node1.IntIdFields.UsedByIds {
-			node2 := &nodes[id2]
-			node1.ElemFields.UsedByDots[ii].LinkAngle = computeLinkAngle(node1, node2)
+			dot := &node1.ElemFields.UsedByDots[ii]
+			angle := computeLinkAngleForDot(displayConfig, node1, &nodes[id2], dot)
+			if horizontal {
+				dot.LinkAngle = getSideLinkAngle(angle)
+			} else {
+				dot.LinkAngle = angle
+			}
 		}
 		sort.Sort(LinkDots(node1.ElemFields.UsedByDots))
 	}
@@ -573,6 +837,7 @@ func handleNodeSorting(algoConfig *AlgoConfigFields, nodes []NodeData) {
 
 // Do all the steps related to creating list of NodeData and filling all the fields.
 // This is the top level function which handles everything.
+// Errors found during the computation are returned as LayoutError (where possible).
 func createComputeAndFillNodeDataList(gdfData *GdfDataStruct) ([]NodeData, error) {
 	nodeDataSeq := createNodeDataList(gdfData)
 
@@ -581,16 +846,45 @@ func createComputeAndFillNodeDataList(gdfData *GdfDataStruct) ([]NodeData, error
 		return nodeDataSeq, err
 	}
 
+	applyTransitiveReduction(&gdfData.AlgoConfig, gdfData.Nodes, nodeDataSeq)
+
 	err = computeLevels(&gdfData.AlgoConfig, nodeDataSeq)
 	if err != nil {
 		return nodeDataSeq, err
 	}
 
-	levelMap := computeShiftsAndGetLevelMap(nodeDataSeq)
+	levelMap, err := computeShiftsAndGetLevelMap(nodeDataSeq, hasLevelHints(gdfData.Nodes))
+	if err != nil {
+		return nodeDataSeq, err
+	}
+	levelMap, waypoints := insertLinkWaypoints(&gdfData.AlgoConfig, levelMap, nodeDataSeq)
+	levelMap = orderNodesInLevels(&gdfData.AlgoConfig, levelMap, nodeDataSeq, waypoints)
+	keepGroupsContiguous(levelMap, nodeDataSeq, waypoints)
+	applyShiftHints(levelMap, nodeDataSeq, waypoints)
 	handleNodeSorting(&gdfData.AlgoConfig, nodeDataSeq)
 	fillElemIdsForAllNodes(nodeDataSeq)
-	computeNodePositionsAndUpdate(&gdfData.DisplayConfig, levelMap, nodeDataSeq)
-	sortDotsToUntangleLinks(nodeDataSeq)
+	displayConfig := &gdfData.DisplayConfig
+	algoConfig := &gdfData.AlgoConfig
+	fillNodeHeights(algoConfig, displayConfig, nodeDataSeq)
+	// With component-layout other than mixed, every component is laid out on its own
+	componentLevelMaps := [][][]int{levelMap}
+	if algoConfig.ComponentLayout != "mixed" {
+		componentLevelMaps = splitLevelMapByComponent(levelMap, nodeDataSeq, waypoints)
+	}
+	for _, componentLevelMap := range componentLevelMaps {
+		computeNodePositionsAndUpdate(algoConfig, displayConfig, componentLevelMap, nodeDataSeq,
+			waypoints)
+		if algoConfig.HorizontalPlacement == "balanced" {
+			computeBalancedPlacementAndUpdate(algoConfig, displayConfig, componentLevelMap,
+				nodeDataSeq, waypoints)
+		}
+	}
+	if len(componentLevelMaps) > 1 {
+		packComponents(algoConfig, displayConfig, componentLevelMaps, nodeDataSeq, waypoints)
+	}
+	makeSpaceForGroupRegions(levelMap, nodeDataSeq, waypoints)
+	fillLinkWaypointRoutes(algoConfig, displayConfig, nodeDataSeq, waypoints)
+	sortDotsToUntangleLinks(algoConfig, displayConfig, nodeDataSeq)
 
 	err = computeResourceLinkFields(gdfData, nodeDataSeq)
 	if err != nil {
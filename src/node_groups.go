@@ -0,0 +1,187 @@
+// This file handles the groups of nodes (group field of the nodes).
+// Nodes of a group are kept next to each other within every level, and every group is shown as
+// labeled regions behind its nodes (one for every level).
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"sort"
+)
+
+// Gap between the nodes of a group and the border of its region (px)
+const groupRegionPaddingPx = 20
+
+// Space for the label of the group, above its nodes (px)
+const groupRegionLabelPx = 28
+
+// A group shown as a region on the board
+type GroupRegionFields struct {
+	Name string
+	// Level of the nodes covered by the region
+	Level int
+	// Label of the group (only on the topmost region of the group)
+	Label string
+	// Color of the border, the label and the background (with low opacity)
+	Color template.CSS
+	// Position and size of the region (px)
+	LeftPx   int
+	TopPx    int
+	WidthPx  int
+	HeightPx int
+}
+
+// Return the names of the groups used by the nodes, in the order of first use
+func getGroupNamesInOrder(nodes []NodeData) []string {
+	names := make([]string, 0, defaultCapacity)
+	seen := map[string]bool{}
+	for idx := range nodes {
+		name := nodes[idx].InputFields.Group
+		if len(name) > 0 && !seen[name] {
+			seen[name] = true
+			pushBack(&names, name)
+		}
+	}
+	return names
+}
+
+// Keep the nodes of every group next to each other within each level. A group is placed based
+// on the average relative position of its nodes in all the levels, so the groups keep the same
+// order in every level. Other nodes and waypoints are placed based on their own position.
+func keepGroupsContiguous(levelMap [][]int, nodes []NodeData, waypoints *LinkWaypoints) {
+	if len(getGroupNamesInOrder(nodes)) == 0 {
+		return
+	}
+
+	getGroup := func(id int) string {
+		if waypoints.isWaypoint(id) {
+			return ""
+		}
+		return nodes[id].InputFields.Group
+	}
+
+	// Relative position (0 to 1) of every id, and the sum of them for every group
+	positions := map[int]float64{}
+	groupSums := map[string]float64{}
+	groupCounts := map[string]int{}
+	for _, ids := range levelMap {
+		for shift, id := range ids {
+			positions[id] = (float64(shift) + 0.5) / float64(len(ids))
+			if group := getGroup(id); len(group) > 0 {
+				groupSums[group] += positions[id]
+				groupCounts[group]++
+			}
+		}
+	}
+
+	getKey := func(id int) float64 {
+		if group := getGroup(id); len(group) > 0 {
+			return groupSums[group] / float64(groupCounts[group])
+		}
+		return positions[id]
+	}
+
+	for _, ids := range levelMap {
+		sort.SliceStable(ids, func(ii int, jj int) bool {
+			firstKey := getKey(ids[ii])
+			secondKey := getKey(ids[jj])
+			if firstKey != secondKey {
+				return firstKey < secondKey
+			}
+			// Same key: keep the nodes of a group together
+			return getGroup(ids[ii]) < getGroup(ids[jj])
+		})
+	}
+	updateShiftsFromLevelMap(levelMap, nodes, waypoints)
+}
+
+// Move all the nodes and waypoints to make space for the group regions at the left and top
+// edges of the board. Nothing is done if there are no groups.
+func makeSpaceForGroupRegions(levelMap [][]int, nodes []NodeData, waypoints *LinkWaypoints) {
+	if len(getGroupNamesInOrder(nodes)) == 0 {
+		return
+	}
+	moveComponent(levelMap, nodes, waypoints, groupRegionPaddingPx,
+		groupRegionPaddingPx+groupRegionLabelPx)
+}
+
+// Compute the regions of all the groups. Nodes of a group are kept together only within every
+// level, so a group gets a region in every level it has nodes in. A region covers the nodes of
+// the group in its level, with some padding. The topmost region of a group also has the label
+// (and space for it on top). Groups without a color get one based on their order.
+func computeGroupRegions(gdfData *GdfDataStruct, nodes []NodeData) []GroupRegionFields {
+	names := getGroupNamesInOrder(nodes)
+	regions := make([]GroupRegionFields, 0, len(names))
+	componentOf := make([]int, len(nodes))
+	if len(names) > 0 && gdfData.AlgoConfig.ComponentLayout != "mixed" {
+		componentOf, _ = findComponents(nodes, &LinkWaypoints{FirstId: len(nodes)})
+	}
+	for idx, name := range names {
+		group := gdfData.Groups[name]
+		color := template.CSS(fmt.Sprintf("hsl(%d, 40%%, 50%%)", (idx*67)%360))
+		if len(group.Color) > 0 {
+			// Color is checked with color_pattern when loading the GDF
+			color = template.CSS(group.Color)
+		}
+
+		// Region in every level, in the order of first use (right and bottom edges are
+		// stored in the width and height until all the nodes are seen). Components laid out
+		// on their own get separate regions.
+		levelRegions := make([]GroupRegionFields, 0, defaultCapacity)
+		regionIdx := map[[2]int]int{}
+		for nodeIdx := range nodes {
+			node := &nodes[nodeIdx]
+			if node.InputFields.Group != name {
+				continue
+			}
+			left := node.ElemFields.LeftPx
+			top := node.ElemFields.TopPx
+			right := left + gdfData.DisplayConfig.NodeBoxWidthPx
+			bottom := top + node.ElemFields.HeightPx
+
+			level := node.Position.Level
+			key := [2]int{componentOf[nodeIdx], level}
+			if _, found := regionIdx[key]; !found {
+				regionIdx[key] = len(levelRegions)
+				pushBack(&levelRegions, GroupRegionFields{Name: name, Level: level, Color: color,
+					LeftPx: left, TopPx: top, WidthPx: right, HeightPx: bottom})
+			}
+			region := &levelRegions[regionIdx[key]]
+			if left < region.LeftPx {
+				region.LeftPx = left
+			}
+			if top < region.TopPx {
+				region.TopPx = top
+			}
+			if right > region.WidthPx {
+				region.WidthPx = right
+			}
+			if bottom > region.HeightPx {
+				region.HeightPx = bottom
+			}
+		}
+
+		labelIdx := 0
+		for regionIdx, region := range levelRegions {
+			first := levelRegions[labelIdx]
+			if region.TopPx < first.TopPx || (region.TopPx == first.TopPx &&
+				region.LeftPx < first.LeftPx) {
+				labelIdx = regionIdx
+			}
+		}
+		for regionIdx := range levelRegions {
+			region := &levelRegions[regionIdx]
+			labelPx := 0
+			if regionIdx == labelIdx {
+				region.Label = group.Label
+				labelPx = groupRegionLabelPx
+			}
+			region.LeftPx -= groupRegionPaddingPx
+			region.TopPx -= groupRegionPaddingPx + labelPx
+			region.WidthPx += groupRegionPaddingPx - region.LeftPx
+			region.HeightPx += groupRegionPaddingPx - region.TopPx
+		}
+		regions = append(regions, levelRegions...)
+	}
+	return regions
+}
@@ -0,0 +1,330 @@
+// This file handles the ordering of nodes within every level (crossing reduction).
+// The levels are computed first. Then the nodes in each level are reordered to reduce the number
+// of link crossings, using layer sweeps (Sugiyama style) with the barycenter or median heuristic.
+package main
+
+import (
+	"sort"
+)
+
+// Max number of sweeps (one sweep goes over all the levels, up or down)
+const maxOrderingSweeps = 24
+
+// Sweeps are stopped if the crossings do not improve for these many sweeps
+const maxOrderingSweepsWithoutGain = 4
+
+// Graph used for ordering the nodes within levels.
+// Ids are the ids used in the levelMap (node index, or waypoint id).
+type orderingGraph struct {
+	// Ids in every level (left to right)
+	levelMap [][]int
+	// Level of every id
+	levels []int
+	// Linked ids in lower levels for every id
+	lower [][]int
+	// Linked ids in higher levels for every id
+	upper [][]int
+}
+
+// Create the ordering graph from the nodes, the waypoints and the initial levelMap.
+// Links with waypoints are added as a chain through the waypoints.
+// The levelMap is copied, so the input is not modified.
+func newOrderingGraph(levelMap [][]int, nodes []NodeData,
+	waypoints *LinkWaypoints) *orderingGraph {
+	numIds := waypoints.numIds()
+	graph := &orderingGraph{
+		levelMap: make([][]int, len(levelMap)),
+		levels:   make([]int, numIds),
+		lower:    make([][]int, numIds),
+		upper:    make([][]int, numIds),
+	}
+	for level, ids := range levelMap {
+		graph.levelMap[level] = append([]int{}, ids...)
+	}
+	for idx := range nodes {
+		graph.levels[idx] = nodes[idx].Position.Level
+	}
+	for idx := range waypoints.List {
+		graph.levels[waypoints.FirstId+idx] = waypoints.List[idx].Position.Level
+	}
+	for idx := range nodes {
+		for _, depId := range nodes[idx].IntIdFields.DependsOnIds {
+			previousId := idx
+			for _, waypointId := range waypoints.getIdsForLink(idx, depId) {
+				graph.addLink(previousId, waypointId)
+				previousId = waypointId
+			}
+			graph.addLink(previousId, depId)
+		}
+	}
+	return graph
+}
+
+// Add a link between two ids (in any order)
+func (graph *orderingGraph) addLink(first int, second int) {
+	if graph.levels[first] > graph.levels[second] {
+		first, second = second, first
+	}
+	pushBack(&graph.upper[first], second)
+	pushBack(&graph.lower[second], first)
+}
+
+// Return the relative horizontal position (0 to 1) of every id.
+// Levels are spread over the same width, so positions in different levels can be compared.
+func (graph *orderingGraph) getRelativePositions() []float64 {
+	positions := make([]float64, len(graph.levels))
+	for _, ids := range graph.levelMap {
+		for shift, id := range ids {
+			positions[id] = (float64(shift) + 0.5) / float64(len(ids))
+		}
+	}
+	return positions
+}
+
+// Count the number of link crossings. Only links between the same pair of levels are compared.
+func (graph *orderingGraph) countCrossings() int {
+	positions := graph.getRelativePositions()
+	// Links grouped by (lower level, upper level)
+	type linkEnds struct {
+		lowerPos float64
+		upperPos float64
+	}
+	linkGroups := map[[2]int][]linkEnds{}
+	for id, upperIds := range graph.upper {
+		for _, upperId := range upperIds {
+			key := [2]int{graph.levels[id], graph.levels[upperId]}
+			linkGroups[key] = append(linkGroups[key], linkEnds{positions[id], positions[upperId]})
+		}
+	}
+
+	crossings := 0
+	for _, links := range linkGroups {
+		for ii := 0; ii < len(links); ii++ {
+			for jj := ii + 1; jj < len(links); jj++ {
+				lowerDiff := links[ii].lowerPos - links[jj].lowerPos
+				upperDiff := links[ii].upperPos - links[jj].upperPos
+				if lowerDiff*upperDiff < 0 {
+					crossings++
+				}
+			}
+		}
+	}
+	return crossings
+}
+
+// Compute the sort key of an id from the positions of its linked ids (on the fixed side).
+// Returns false if the id has no linked ids.
+func getOrderingKey(heuristic string, linkedIds []int, positions []float64) (float64, bool) {
+	if len(linkedIds) == 0 {
+		return 0, false
+	}
+	values := make([]float64, 0, len(linkedIds))
+	for _, linkedId := range linkedIds {
+		pushBack(&values, positions[linkedId])
+	}
+
+	if heuristic == "median" {
+		sort.Float64s(values)
+		mid := len(values) / 2
+		if len(values)%2 == 1 {
+			return values[mid], true
+		}
+		return (values[mid-1] + values[mid]) / 2, true
+	}
+
+	// barycenter
+	sum := 0.0
+	for _, value := range values {
+		sum += value
+	}
+	return sum / float64(len(values)), true
+}
+
+// Reorder the ids of a single level based on the linked ids in the lower levels (useLower=true)
+// or the upper levels. Ids without links keep their current position.
+func (graph *orderingGraph) reorderLevel(heuristic string, level int, useLower bool) {
+	positions := graph.getRelativePositions()
+	ids := graph.levelMap[level]
+	keys := make(map[int]float64, len(ids))
+	for _, id := range ids {
+		linkedIds := graph.upper[id]
+		if useLower {
+			linkedIds = graph.lower[id]
+		}
+		key, ok := getOrderingKey(heuristic, linkedIds, positions)
+		if !ok {
+			key = positions[id]
+		}
+		keys[id] = key
+	}
+	sort.SliceStable(ids, func(ii int, jj int) bool {
+		return keys[ids[ii]] < keys[ids[jj]]
+	})
+}
+
+// Go over all the levels once. Upward sweep uses the lower levels as reference.
+func (graph *orderingGraph) sweep(heuristic string, upward bool) {
+	numLevels := len(graph.levelMap)
+	if upward {
+		for level := 1; level < numLevels; level++ {
+			graph.reorderLevel(heuristic, level, true)
+		}
+	} else {
+		for level := numLevels - 2; level >= 0; level-- {
+			graph.reorderLevel(heuristic, level, false)
+		}
+	}
+}
+
+// Copy of the current levelMap
+func (graph *orderingGraph) copyLevelMap() [][]int {
+	result := make([][]int, len(graph.levelMap))
+	for level, ids := range graph.levelMap {
+		result[level] = append([]int{}, ids...)
+	}
+	return result
+}
+
+// Reduce crossings with alternating up and down sweeps. The best order found is kept.
+func (graph *orderingGraph) reduceCrossings(heuristic string) {
+	bestLevelMap := graph.copyLevelMap()
+	bestCrossings := graph.countCrossings()
+	sweepsWithoutGain := 0
+	for step := 0; step < maxOrderingSweeps && bestCrossings > 0; step++ {
+		graph.sweep(heuristic, step%2 == 0)
+		crossings := graph.countCrossings()
+		if crossings < bestCrossings {
+			bestCrossings = crossings
+			bestLevelMap = graph.copyLevelMap()
+			sweepsWithoutGain = 0
+			continue
+		}
+		sweepsWithoutGain++
+		if sweepsWithoutGain >= maxOrderingSweepsWithoutGain {
+			break
+		}
+	}
+	graph.levelMap = bestLevelMap
+}
+
+// Reorder the nodes (and waypoints) within every level according to algo-config node-ordering.
+// With "declaration", the order from the GDF is kept. The shifts of the nodes and waypoints
+// are updated to match the returned levelMap.
+func orderNodesInLevels(algoConfig *AlgoConfigFields, levelMap [][]int, nodes []NodeData,
+	waypoints *LinkWaypoints) [][]int {
+	if algoConfig.NodeOrdering == "declaration" {
+		return levelMap
+	}
+
+	graph := newOrderingGraph(levelMap, nodes, waypoints)
+	graph.reduceCrossings(algoConfig.NodeOrdering)
+	updateShiftsFromLevelMap(graph.levelMap, nodes, waypoints)
+	return graph.levelMap
+}
+
+// Set the shifts of all the nodes and waypoints based on their index in the levelMap
+func updateShiftsFromLevelMap(levelMap [][]int, nodes []NodeData, waypoints *LinkWaypoints) {
+	for _, ids := range levelMap {
+		for shift, id := range ids {
+			if waypoints.isWaypoint(id) {
+				waypoints.get(id).Position.Shift = shift
+			} else {
+				nodes[id].Position.Shift = shift
+			}
+		}
+	}
+}
+
+// Move the nodes with a shift hint to the given position within their level. Nodes with a
+// smaller shift are placed first. A shift beyond the end of the level makes the node the last.
+// Groups (kept together by keepGroupsContiguous) are not split: for a node in a group, the
+// shift is its position among the nodes of the group in that level. A node outside groups is
+// placed after the group if its shift falls within the group.
+func applyShiftHints(levelMap [][]int, nodes []NodeData, waypoints *LinkWaypoints) {
+	hasShift := func(id int) bool {
+		return !waypoints.isWaypoint(id) && nodes[id].InputFields.Shift != nil
+	}
+	getShift := func(id int) int {
+		return *nodes[id].InputFields.Shift
+	}
+	getGroup := func(id int) string {
+		if waypoints.isWaypoint(id) {
+			return ""
+		}
+		return nodes[id].InputFields.Group
+	}
+
+	for level, ids := range levelMap {
+		// Every group is a single block. Other ids get a block of their own.
+		blocks := make([][]int, 0, len(ids))
+		for _, id := range ids {
+			group := getGroup(id)
+			if len(group) > 0 && len(blocks) > 0 && getGroup(blocks[len(blocks)-1][0]) == group {
+				last := &blocks[len(blocks)-1]
+				*last = append(*last, id)
+				continue
+			}
+			pushBack(&blocks, []int{id})
+		}
+
+		pinnedIds := make([]int, 0)
+		otherBlocks := make([][]int, 0, len(blocks))
+		for _, block := range blocks {
+			if len(getGroup(block[0])) > 0 {
+				// Shift hints within the group
+				pushBack(&otherBlocks, insertPinnedIds(block, hasShift, getShift))
+			} else if hasShift(block[0]) {
+				pushBack(&pinnedIds, block[0])
+			} else {
+				pushBack(&otherBlocks, block)
+			}
+		}
+		result := make([]int, 0, len(ids))
+		for _, block := range insertPinnedBlocks(otherBlocks, pinnedIds, getShift) {
+			result = append(result, block...)
+		}
+		levelMap[level] = result
+	}
+	updateShiftsFromLevelMap(levelMap, nodes, waypoints)
+}
+
+// Move the pinned ids (hasShift) of the list to the positions given by getShift
+func insertPinnedIds(ids []int, hasShift func(int) bool, getShift func(int) int) []int {
+	pinnedIds := make([]int, 0)
+	otherBlocks := make([][]int, 0, len(ids))
+	for _, id := range ids {
+		if hasShift(id) {
+			pushBack(&pinnedIds, id)
+		} else {
+			pushBack(&otherBlocks, []int{id})
+		}
+	}
+	if len(pinnedIds) == 0 {
+		return ids
+	}
+
+	result := make([]int, 0, len(ids))
+	for _, block := range insertPinnedBlocks(otherBlocks, pinnedIds, getShift) {
+		result = append(result, block...)
+	}
+	return result
+}
+
+// Insert every pinned id as a block of its own, at the first position between the blocks with
+// at least getShift(id) ids before it. Pinned ids with a smaller shift are inserted first.
+func insertPinnedBlocks(blocks [][]int, pinnedIds []int, getShift func(int) int) [][]int {
+	sort.SliceStable(pinnedIds, func(ii int, jj int) bool {
+		return getShift(pinnedIds[ii]) < getShift(pinnedIds[jj])
+	})
+	result := blocks
+	for _, id := range pinnedIds {
+		position := 0
+		count := 0
+		for position < len(result) && count < getShift(id) {
+			count += len(result[position])
+			position++
+		}
+		result = append(result[:position], append([][]int{{id}}, result[position:]...)...)
+	}
+	return result
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+// Create nodes for the levelMap (without waypoints). links has (node id, dependency id) pairs.
+func makeTestLevelNodes(levelMap [][]int, links [][2]int) ([]NodeData, *LinkWaypoints) {
+	numNodes := 0
+	for _, ids := range levelMap {
+		numNodes += len(ids)
+	}
+	nodes := make([]NodeData, numNodes)
+	for level, ids := range levelMap {
+		for shift, id := range ids {
+			nodes[id].Position = NodePositionFields{level, shift}
+		}
+	}
+	for _, link := range links {
+		pushBack(&nodes[link[0]].IntIdFields.DependsOnIds, link[1])
+	}
+	return nodes, &LinkWaypoints{FirstId: numNodes}
+}
+
+func TestGetOrderingKey(t *testing.T) {
+	positions := []float64{0.1, 0.9, 0.5, 0.2}
+	tests := []struct {
+		heuristic string
+		linkedIds []int
+		want      float64
+		wantOk    bool
+	}{
+		{"median", []int{0, 1, 2}, 0.5, true},
+		{"median", []int{0, 3}, 0.15, true},
+		{"barycenter", []int{0, 1, 2}, 0.5, true},
+		{"barycenter", []int{1, 3}, 0.55, true},
+		{"barycenter", []int{}, 0, false},
+	}
+	for _, test := range tests {
+		got, ok := getOrderingKey(test.heuristic, test.linkedIds, positions)
+		if ok != test.wantOk || got < test.want-1e-9 || got > test.want+1e-9 {
+			t.Errorf("%s %v: got (%v, %v), want (%v, %v)", test.heuristic, test.linkedIds,
+				got, ok, test.want, test.wantOk)
+		}
+	}
+}
+
+func TestReduceCrossings(t *testing.T) {
+	tests := []struct {
+		name      string
+		levelMap  [][]int
+		links     [][2]int
+		heuristic string
+		// Crossings before and after the reduction
+		before int
+		after  int
+		// Expected levelMap after the reduction (nil to skip the check)
+		want [][]int
+	}{
+		{
+			name:      "single crossing removed",
+			levelMap:  [][]int{{0, 1}, {2, 3}},
+			links:     [][2]int{{2, 1}, {3, 0}},
+			heuristic: "barycenter",
+			before:    1,
+			after:     0,
+			want:      [][]int{{0, 1}, {3, 2}},
+		},
+		{
+			name:      "fully reversed level",
+			levelMap:  [][]int{{0, 1, 2}, {3, 4, 5}},
+			links:     [][2]int{{3, 2}, {4, 1}, {5, 0}},
+			heuristic: "median",
+			before:    3,
+			after:     0,
+			want:      [][]int{{0, 1, 2}, {5, 4, 3}},
+		},
+		{
+			name:      "unavoidable crossing kept",
+			levelMap:  [][]int{{0, 1}, {2, 3}},
+			links:     [][2]int{{2, 0}, {2, 1}, {3, 0}, {3, 1}},
+			heuristic: "barycenter",
+			before:    1,
+			after:     1,
+			want:      [][]int{{0, 1}, {2, 3}},
+		},
+		{
+			name:      "three levels",
+			levelMap:  [][]int{{0, 1}, {2, 3}, {4, 5}},
+			links:     [][2]int{{2, 1}, {3, 0}, {4, 2}, {5, 3}},
+			heuristic: "barycenter",
+			before:    1,
+			after:     0,
+			want:      [][]int{{0, 1}, {3, 2}, {5, 4}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			nodes, waypoints := makeTestLevelNodes(test.levelMap, test.links)
+			graph := newOrderingGraph(test.levelMap, nodes, waypoints)
+			if got := graph.countCrossings(); got != test.before {
+				t.Errorf("crossings before: got %d, want %d", got, test.before)
+			}
+			graph.reduceCrossings(test.heuristic)
+			if got := graph.countCrossings(); got != test.after {
+				t.Errorf("crossings after: got %d, want %d", got, test.after)
+			}
+			if test.want != nil && !reflect.DeepEqual(graph.levelMap, test.want) {
+				t.Errorf("levelMap: got %v, want %v", graph.levelMap, test.want)
+			}
+		})
+	}
+}
+
+func TestOrderNodesInLevelsUpdatesShifts(t *testing.T) {
+	levelMap := [][]int{{0, 1}, {2, 3}}
+	nodes, waypoints := makeTestLevelNodes(levelMap, [][2]int{{2, 1}, {3, 0}})
+
+	algoConfig := AlgoConfigFields{NodeOrdering: "barycenter"}
+	result := orderNodesInLevels(&algoConfig, levelMap, nodes, waypoints)
+	if !reflect.DeepEqual(result, [][]int{{0, 1}, {3, 2}}) {
+		t.Fatalf("unexpected levelMap: %v", result)
+	}
+	if nodes[3].Position.Shift != 0 || nodes[2].Position.Shift != 1 {
+		t.Errorf("shifts not updated: node 2 at %d, node 3 at %d",
+			nodes[2].Position.Shift, nodes[3].Position.Shift)
+	}
+	// The input levelMap is not modified
+	if !reflect.DeepEqual(levelMap, [][]int{{0, 1}, {2, 3}}) {
+		t.Errorf("input levelMap modified: %v", levelMap)
+	}
+}
+
+func TestApplyShiftHints(t *testing.T) {
+	tests := []struct {
+		name string
+		// Group of every node (nodes 0 to 3 are in a single level)
+		groups []string
+		// Shift hints (node id -> shift)
+		shifts map[int]int
+		want   []int
+	}{
+		{"no hints", []string{"", "", "", ""}, map[int]int{}, []int{0, 1, 2, 3}},
+		{"move to the front", []string{"", "", "", ""}, map[int]int{3: 0}, []int{3, 0, 1, 2}},
+		{"beyond the end", []string{"", "", "", ""}, map[int]int{0: 10}, []int{1, 2, 3, 0}},
+		{"two hints", []string{"", "", "", ""}, map[int]int{3: 0, 2: 1}, []int{3, 2, 0, 1}},
+		{"shift within a group moved after it", []string{"", "g", "g", ""}, map[int]int{0: 1},
+			[]int{1, 2, 0, 3}},
+		{"shift before a group", []string{"", "g", "g", ""}, map[int]int{3: 1},
+			[]int{0, 3, 1, 2}},
+		{"node in a group", []string{"", "g", "g", ""}, map[int]int{2: 0}, []int{0, 2, 1, 3}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			levelMap := [][]int{{0, 1, 2, 3}}
+			nodes, waypoints := makeTestLevelNodes(levelMap, nil)
+			for id, group := range test.groups {
+				nodes[id].InputFields.Group = group
+			}
+			for id, shift := range test.shifts {
+				shift := shift
+				nodes[id].InputFields.Shift = &shift
+			}
+
+			applyShiftHints(levelMap, nodes, waypoints)
+			if !reflect.DeepEqual(levelMap[0], test.want) {
+				t.Errorf("got %v, want %v", levelMap[0], test.want)
+			}
+			for shift, id := range levelMap[0] {
+				if nodes[id].Position.Shift != shift {
+					t.Errorf("node %d: shift %d, want %d", id, nodes[id].Position.Shift, shift)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+// This file handles the horizontal placement of nodes with algo-config
+// horizontal-placement: balanced.
+// The default placement spreads every level evenly over the width of the widest level. The
+// balanced placement moves every node close to the median of its linked nodes (in the spirit
+// of Brandes-Köpf), while keeping the order within levels and the minimum horizontal step.
+package main
+
+import (
+	"math"
+	"sort"
+)
+
+// Number of up and down sweeps for the balanced placement. The final sweep uses both sides.
+const balancedPlacementSweeps = 8
+
+// Place the items of a level (in order) as close as possible to the desired positions, with
+// at least minGap between neighbors. The result minimizes the sum of squared distances to the
+// desired positions (pool adjacent violators algorithm).
+func placeWithMinGap(desired []float64, minGap float64) []float64 {
+	// Subtracting idx*minGap turns the gap constraint into a simple ordering constraint
+	type pooledBlock struct {
+		sum   float64
+		count int
+	}
+	blocks := make([]pooledBlock, 0, len(desired))
+	for idx, value := range desired {
+		pushBack(&blocks, pooledBlock{value - float64(idx)*minGap, 1})
+		for len(blocks) > 1 {
+			last := blocks[len(blocks)-1]
+			prev := blocks[len(blocks)-2]
+			if prev.sum/float64(prev.count) <= last.sum/float64(last.count) {
+				break
+			}
+			blocks = blocks[:len(blocks)-2]
+			pushBack(&blocks, pooledBlock{prev.sum + last.sum, prev.count + last.count})
+		}
+	}
+
+	result := make([]float64, 0, len(desired))
+	for _, block := range blocks {
+		mean := block.sum / float64(block.count)
+		for count := 0; count < block.count; count++ {
+			pushBack(&result, mean+float64(len(result))*minGap)
+		}
+	}
+	return result
+}
+
+// Median of the positions of the given ids. Returns false if there are no ids.
+func getMedianPosition(ids []int, positions []float64) (float64, bool) {
+	if len(ids) == 0 {
+		return 0, false
+	}
+	values := make([]float64, 0, len(ids))
+	for _, id := range ids {
+		pushBack(&values, positions[id])
+	}
+	sort.Float64s(values)
+	mid := len(values) / 2
+	if len(values)%2 == 1 {
+		return values[mid], true
+	}
+	return (values[mid-1] + values[mid]) / 2, true
+}
+
+// Move the ids of a single level towards the median of their linked ids. Linked ids are taken
+// from the lower levels, upper levels, or both.
+func placeLevel(graph *orderingGraph, level int, useLower bool, useUpper bool,
+	positions []float64, minGap float64) {
+	ids := graph.levelMap[level]
+	desired := make([]float64, 0, len(ids))
+	for _, id := range ids {
+		linkedIds := make([]int, 0, defaultCapacity)
+		if useLower {
+			linkedIds = append(linkedIds, graph.lower[id]...)
+		}
+		if useUpper {
+			linkedIds = append(linkedIds, graph.upper[id]...)
+		}
+		value, ok := getMedianPosition(linkedIds, positions)
+		if !ok {
+			value = positions[id]
+		}
+		pushBack(&desired, value)
+	}
+
+	for shift, value := range placeWithMinGap(desired, minGap) {
+		positions[ids[shift]] = value
+	}
+}
+
+// Return the position of a node (or waypoint) across the levels: left, or top with
+// orientation: horizontal
+func getCrossPositionPx(algoConfig *AlgoConfigFields, leftPx *int, topPx *int) *int {
+	if algoConfig.Orientation == "horizontal" {
+		return topPx
+	}
+	return leftPx
+}
+
+// Compute the positions for horizontal-placement: balanced and update the nodes and waypoints.
+// Only the position across the levels is changed (left, or top with orientation: horizontal).
+// Must be called after computeNodePositionsAndUpdate (the positions from there are used as the
+// starting point).
+func computeBalancedPlacementAndUpdate(algoConfig *AlgoConfigFields,
+	displayConfig *DisplayConfigFields, levelMap [][]int, nodes []NodeData,
+	waypoints *LinkWaypoints) {
+	graph := newOrderingGraph(levelMap, nodes, waypoints)
+	minGap := float64(getCrossStepPx(algoConfig, displayConfig, nodes))
+
+	// Pointers to the positions to be updated
+	positionPtrs := make([]*int, waypoints.numIds())
+	for idx := range nodes {
+		elemFields := &nodes[idx].ElemFields
+		positionPtrs[idx] = getCrossPositionPx(algoConfig, &elemFields.LeftPx, &elemFields.TopPx)
+	}
+	for idx := range waypoints.List {
+		waypoint := &waypoints.List[idx]
+		positionPtrs[waypoints.FirstId+idx] = getCrossPositionPx(algoConfig, &waypoint.LeftPx,
+			&waypoint.TopPx)
+	}
+	positions := make([]float64, len(positionPtrs))
+	for id, ptr := range positionPtrs {
+		positions[id] = float64(*ptr)
+	}
+
+	numLevels := len(levelMap)
+	for step := 0; step < balancedPlacementSweeps; step++ {
+		if step%2 == 0 {
+			for level := 1; level < numLevels; level++ {
+				placeLevel(graph, level, true, false, positions, minGap)
+			}
+		} else {
+			for level := numLevels - 2; level >= 0; level-- {
+				placeLevel(graph, level, false, true, positions, minGap)
+			}
+		}
+	}
+	for level := 0; level < numLevels; level++ {
+		placeLevel(graph, level, true, true, positions, minGap)
+	}
+
+	// Move everything so that the first item is at 0. Only the ids in the levelMap are updated
+	// (the levelMap can have a part of the graph).
+	minPosition := math.Inf(1)
+	for _, ids := range levelMap {
+		for _, id := range ids {
+			minPosition = math.Min(minPosition, positions[id])
+		}
+	}
+	for _, ids := range levelMap {
+		for _, id := range ids {
+			*positionPtrs[id] = int(math.Round(positions[id] - minPosition))
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPlaceWithMinGap(t *testing.T) {
+	tests := []struct {
+		name    string
+		desired []float64
+		minGap  float64
+		want    []float64
+	}{
+		{"empty", []float64{}, 1, []float64{}},
+		{"single", []float64{4}, 1, []float64{4}},
+		{"gaps already satisfied", []float64{0, 2, 5}, 1, []float64{0, 2, 5}},
+		{"all at the same position", []float64{0, 0, 0}, 1, []float64{-1, 0, 1}},
+		{"swapped pair", []float64{3, 0}, 2, []float64{0.5, 2.5}},
+		{"violation in the middle", []float64{0, 5, 4}, 2, []float64{0, 3.5, 5.5}},
+		{"two separate violations", []float64{0, 0, 10, 10}, 2, []float64{-1, 1, 9, 11}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := placeWithMinGap(test.desired, test.minGap)
+			if len(got) != len(test.want) {
+				t.Fatalf("got %v, want %v", got, test.want)
+			}
+			for idx := range got {
+				if math.Abs(got[idx]-test.want[idx]) > 1e-9 {
+					t.Fatalf("got %v, want %v", got, test.want)
+				}
+				if idx > 0 && got[idx]-got[idx-1] < test.minGap-1e-9 {
+					t.Errorf("gap between %d and %d is less than %v: %v", idx-1, idx,
+						test.minGap, got)
+				}
+			}
+		})
+	}
+}
+
+func TestGetMedianPosition(t *testing.T) {
+	positions := []float64{10, 40, 20, 30}
+	tests := []struct {
+		ids    []int
+		want   float64
+		wantOk bool
+	}{
+		{[]int{}, 0, false},
+		{[]int{1}, 40, true},
+		{[]int{0, 1, 2}, 20, true},
+		{[]int{0, 1, 2, 3}, 25, true},
+	}
+	for _, test := range tests {
+		got, ok := getMedianPosition(test.ids, positions)
+		if got != test.want || ok != test.wantOk {
+			t.Errorf("%v: got (%v, %v), want (%v, %v)", test.ids, got, ok, test.want,
+				test.wantOk)
+		}
+	}
+}
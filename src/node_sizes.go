@@ -0,0 +1,152 @@
+// This file handles the height of nodes (display-config: node-box-height-px, node-height).
+// With node-height: auto, the height of every node is estimated from the length of its title
+// and subtitle, so that long texts are not clipped. Levels are then spaced based on the tallest
+// node in them.
+package main
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Smallest node-box-height-px allowed: space for the link panels and a line of text
+const minNodeBoxHeightPx = 80
+
+// Estimates for the size of the parts of a node (px), based on style.css with the default font
+// size of 16px
+const (
+	// Size of a link panel (height, or width with orientation: horizontal)
+	linkPanelSizePx = 20
+	// Borders of the node content and some space around the text
+	nodeFrameHeightPx = 24
+	// Borders and margins on the sides of the text
+	nodeTextPaddingPx = 14
+	// Margins above and below the title or the subtitle
+	nodeTextMarginPx     = 10
+	titleCharWidthPx     = 11
+	titleLineHeightPx    = 23
+	subtitleCharWidthPx  = 9
+	subtitleLineHeightPx = 19
+)
+
+// Estimate the number of lines for the text when wrapped at charsPerLine characters.
+// Words longer than a line are split.
+func estimateNumLines(text string, charsPerLine int) int {
+	if charsPerLine < 1 {
+		charsPerLine = 1
+	}
+	numLines := 0
+	lineLen := 0
+	for _, word := range strings.Fields(text) {
+		size := utf8.RuneCountInString(word)
+		if numLines == 0 {
+			numLines = 1
+		} else if lineLen+1+size > charsPerLine {
+			numLines++
+			lineLen = 0
+		} else {
+			lineLen++
+		}
+		lineLen += size
+		for lineLen > charsPerLine {
+			numLines++
+			lineLen -= charsPerLine
+		}
+	}
+	return numLines
+}
+
+// Estimate the height of the node (px) needed for its title and subtitle
+func estimateNodeHeightPx(algoConfig *AlgoConfigFields, displayConfig *DisplayConfigFields,
+	node *NodeData) int {
+	textWidthPx := displayConfig.NodeBoxWidthPx - nodeTextPaddingPx
+	heightPx := nodeFrameHeightPx
+	if algoConfig.Orientation == "horizontal" {
+		// Link panels are on the sides
+		textWidthPx -= 2 * linkPanelSizePx
+	} else {
+		heightPx += 2 * linkPanelSizePx
+	}
+
+	numTitleLines := estimateNumLines(node.InputFields.Title, textWidthPx/titleCharWidthPx)
+	heightPx += numTitleLines*titleLineHeightPx + nodeTextMarginPx
+	if len(node.InputFields.Subtitle) > 0 {
+		numSubtitleLines := estimateNumLines(node.InputFields.Subtitle,
+			textWidthPx/subtitleCharWidthPx)
+		heightPx += numSubtitleLines*subtitleLineHeightPx + nodeTextMarginPx
+	}
+	return heightPx
+}
+
+// Fill the height of all the nodes. With node-height: auto, the estimated height is used if it
+// is more than node-box-height-px.
+func fillNodeHeights(algoConfig *AlgoConfigFields, displayConfig *DisplayConfigFields,
+	nodes []NodeData) {
+	for idx := range nodes {
+		node := &nodes[idx]
+		node.ElemFields.HeightPx = displayConfig.NodeBoxHeightPx
+		if displayConfig.NodeHeight != "auto" {
+			continue
+		}
+		estimatePx := estimateNodeHeightPx(algoConfig, displayConfig, node)
+		if estimatePx > node.ElemFields.HeightPx {
+			node.ElemFields.HeightPx = estimatePx
+		}
+	}
+}
+
+// Return the height of the tallest node among the ids (from the levelMap). Waypoints and
+// empty levels use node-box-height-px.
+func getMaxNodeHeightPx(displayConfig *DisplayConfigFields, ids []int, nodes []NodeData,
+	waypoints *LinkWaypoints) int {
+	maxHeightPx := displayConfig.NodeBoxHeightPx
+	for _, id := range ids {
+		if waypoints.isWaypoint(id) {
+			continue
+		}
+		if nodes[id].ElemFields.HeightPx > maxHeightPx {
+			maxHeightPx = nodes[id].ElemFields.HeightPx
+		}
+	}
+	return maxHeightPx
+}
+
+// Return the step (px) between the nodes within a level.
+// With orientation: horizontal, levels are placed left to right, and nodes within a level are
+// placed top to bottom. Then the step grows with the tallest node.
+func getCrossStepPx(algoConfig *AlgoConfigFields, displayConfig *DisplayConfigFields,
+	nodes []NodeData) int {
+	if algoConfig.Orientation != "horizontal" {
+		return displayConfig.HorizontalStepPx
+	}
+	maxHeightPx := displayConfig.NodeBoxHeightPx
+	for idx := range nodes {
+		if nodes[idx].ElemFields.HeightPx > maxHeightPx {
+			maxHeightPx = nodes[idx].ElemFields.HeightPx
+		}
+	}
+	return displayConfig.VerticalStepPx + maxHeightPx - displayConfig.NodeBoxHeightPx
+}
+
+// Return the position (px) of every level along the level axis: top, or left with
+// orientation: horizontal. With the default orientation, the highest level is at the top, and
+// the step after a level grows with the tallest node in it (so the gap between the levels is
+// kept).
+func computeLevelOffsetsPx(algoConfig *AlgoConfigFields, displayConfig *DisplayConfigFields,
+	levelMap [][]int, nodes []NodeData, waypoints *LinkWaypoints) []int {
+	offsets := make([]int, len(levelMap))
+	if algoConfig.Orientation == "horizontal" {
+		for level := range levelMap {
+			offsets[level] = level * displayConfig.HorizontalStepPx
+		}
+		return offsets
+	}
+
+	topPx := 0
+	for level := len(levelMap) - 1; level >= 0; level-- {
+		offsets[level] = topPx
+		maxHeightPx := getMaxNodeHeightPx(displayConfig, levelMap[level], nodes, waypoints)
+		topPx += displayConfig.VerticalStepPx + maxHeightPx - displayConfig.NodeBoxHeightPx
+	}
+	return offsets
+}
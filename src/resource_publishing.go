@@ -0,0 +1,206 @@
+// This file handles the local resources (files referred in the `resources` section of GDF).
+// When the output directory is different from the input directory, every local resource file is
+// copied to the output directory and the resource links are rewritten, so that the output
+// directory is self-contained.
+// For single-file output, small local images can be inlined as data URLs instead.
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Local resources that cannot keep their relative path are copied to this dir inside outdir
+const publishedResourceDirName = "linkitall_resources"
+
+// Local images larger than this are not inlined
+const maxInlineImageSizeBytes = 256 * 1024
+
+// MIME types of the images that can be inlined (same as the images handled by main.js)
+var inlineImageMimeTypes = map[string]string{
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".png":  "image/png",
+	".gif":  "image/gif",
+	".bmp":  "image/bmp",
+	".svg":  "image/svg+xml",
+}
+
+// Matches links with a scheme like https:, mailto:, data: etc.
+var url_scheme_pattern = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9+.-]*:`)
+
+// Matches windows paths with a drive letter like C:\dir\file.pdf or C:/dir/file.pdf
+var drive_path_pattern = regexp.MustCompile(`^[a-zA-Z]:[\\/]`)
+
+// Check if the path is absolute. Windows paths with a drive letter are treated as absolute
+// on every OS (filepath.IsAbs only reports them on windows).
+func isAbsResourcePath(path string) bool {
+	return filepath.IsAbs(path) || drive_path_pattern.MatchString(path)
+}
+
+// Check if the resource link refers to a local file (and not a web link)
+func isLocalResource(link string) bool {
+	if len(link) == 0 || strings.HasPrefix(link, "#") || strings.HasPrefix(link, "//") {
+		return false
+	}
+	if isAbsResourcePath(link) {
+		return true
+	}
+	return !url_scheme_pattern.MatchString(link)
+}
+
+// Split the resource link into file path and the suffix (?query or #fragment) if any.
+// Example: "resources/doc.pdf#view=fit" -> ("resources/doc.pdf", "#view=fit")
+func splitResourceLink(link string) (string, string) {
+	idx := strings.IndexAny(link, "?#")
+	if idx < 0 {
+		return link, ""
+	}
+	return link[:idx], link[idx:]
+}
+
+// Return the path to the file referred by a local resource link.
+// Relative links are resolved against indir.
+func getLocalResourceFilePath(indir string, link string) string {
+	filePart, _ := splitResourceLink(link)
+	if !isAbsResourcePath(filePart) {
+		filePart = filepath.Join(indir, filepath.FromSlash(filePart))
+	}
+	return filepath.Clean(filePart)
+}
+
+// Copy a single file. The copy is skipped if the target has the same size and modification
+// time as the source (this happens on every rebuild in serve mode).
+func copyFileIfChanged(srcPath string, targetPath string) error {
+	srcStat, err := os.Stat(srcPath)
+	if err != nil {
+		return err
+	}
+	if srcStat.IsDir() {
+		return fmt.Errorf("expected a file, got a directory: %s", srcPath)
+	}
+
+	targetStat, err := os.Stat(targetPath)
+	if err == nil && targetStat.Size() == srcStat.Size() &&
+		targetStat.ModTime().Equal(srcStat.ModTime()) {
+		return nil
+	}
+
+	err = os.MkdirAll(filepath.Dir(targetPath), 0755)
+	if err != nil {
+		return err
+	}
+
+	src, err := os.Open(srcPath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	target, err := os.Create(targetPath)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(target, src)
+	closeErr := target.Close()
+	if err != nil {
+		return err
+	}
+	if closeErr != nil {
+		return closeErr
+	}
+
+	return os.Chtimes(targetPath, srcStat.ModTime(), srcStat.ModTime())
+}
+
+// Decide the path (relative to outdir) of the published copy of a local resource file.
+// Files inside indir keep their relative path. Others are moved into linkitall_resources.
+// `used` keeps track of target paths already assigned to other files.
+func getPublishedResourcePath(indir string, srcPath string, resourceName string,
+	used map[string]string) string {
+	relPath, err := filepath.Rel(indir, srcPath)
+	if err == nil && filepath.IsLocal(relPath) {
+		return relPath
+	}
+
+	baseName := filepath.Base(srcPath)
+	relPath = filepath.Join(publishedResourceDirName, baseName)
+	if usedBy, ok := used[relPath]; ok && usedBy != srcPath {
+		// Resource names are unique. Use them to avoid collisions.
+		relPath = filepath.Join(publishedResourceDirName, resourceName+"_"+baseName)
+	}
+	return relPath
+}
+
+// Copy all the local resources to outdir and rewrite their links in the resource config.
+// Resource paths in the GDF are relative to indir (the location of the original output file).
+func publishLocalResources(gdfData *GdfDataStruct, indir string, outdir string) error {
+	// published path (relative to outdir) -> source path
+	used := map[string]string{}
+	// Sorted, so that the published paths do not change from one run to another
+	names := make([]string, 0, len(gdfData.ResourceConfig))
+	for name := range gdfData.ResourceConfig {
+		pushBack(&names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		link := gdfData.ResourceConfig[name]
+		if !isLocalResource(link) {
+			continue
+		}
+
+		srcPath := getLocalResourceFilePath(indir, link)
+		_, suffix := splitResourceLink(link)
+		relPath := getPublishedResourcePath(indir, srcPath, name, used)
+		used[relPath] = srcPath
+
+		err := copyFileIfChanged(srcPath, filepath.Join(outdir, relPath))
+		if err != nil {
+			return fmt.Errorf("unable to publish resource %s: %w", name, err)
+		}
+		gdfData.ResourceConfig[name] = filepath.ToSlash(relPath) + suffix
+	}
+
+	return nil
+}
+
+// Replace the links of small local images in the resource config with base64 data URLs.
+// Larger images and other resources are left as they are.
+func inlineSmallImageResources(gdfData *GdfDataStruct, indir string) error {
+	for name, link := range gdfData.ResourceConfig {
+		if !isLocalResource(link) {
+			continue
+		}
+
+		srcPath := getLocalResourceFilePath(indir, link)
+		mimeType, ok := inlineImageMimeTypes[strings.ToLower(filepath.Ext(srcPath))]
+		if !ok {
+			continue
+		}
+
+		stat, err := os.Stat(srcPath)
+		if err != nil {
+			return fmt.Errorf("unable to inline resource %s: %w", name, err)
+		}
+		if stat.Size() > maxInlineImageSizeBytes {
+			continue
+		}
+
+		data, err := os.ReadFile(srcPath)
+		if err != nil {
+			return fmt.Errorf("unable to inline resource %s: %w", name, err)
+		}
+		encoded := base64.StdEncoding.EncodeToString(data)
+		gdfData.ResourceConfig[name] = fmt.Sprintf("data:%s;base64,%s", mimeType, encoded)
+	}
+
+	return nil
+}